@@ -0,0 +1,260 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// LimitSample is the result of an execution, passed to LimitAlgorithm
+type LimitSample struct {
+	// Time the execution started, by the clock of AdaptiveLimiter.
+	Started time.Time
+	// Execution time.
+	RTT time.Duration
+	// Number of executions running when it started, including itself.
+	InFlight int
+	// Reports whether the execution failed with an overload error, see
+	// AdaptiveLimiterConfig.IsOverload.
+	Overload bool
+}
+
+// LimitAlgorithm computes concurrency limit of AdaptiveLimiter
+//
+// Update is called after every execution with the lock of AdaptiveLimiter held,
+// so an implementation can keep state without locking. As a result, an instance
+// must not be shared by several AdaptiveLimiters. Returned limit is clamped by
+// AdaptiveLimiter.
+type LimitAlgorithm interface {
+	Update(limit float64, s LimitSample) float64
+}
+
+type aimd struct {
+	ratio      float64
+	maxLatency time.Duration
+	decreased  time.Time // when limit is decreased last time
+}
+
+// AIMD creates a LimitAlgorithm using additive-increase/multiplicative-decrease
+//
+// Limit is multiplied by ratio (default to 0.9 if not in (0, 1)) on overload or
+// if RTT exceeds maxLatency (maxLatency <= 0 means no latency check). Otherwise,
+// it is increased by 1 if at least half of the limit is in use.
+//
+// Like TCP, limit is decreased at most once per RTT: executions started before
+// last decrease are likely failed by same outage, so they are ignored.
+func AIMD(ratio float64, maxLatency time.Duration) LimitAlgorithm {
+	if ratio <= 0 || ratio >= 1 {
+		ratio = 0.9
+	}
+	return &aimd{ratio: ratio, maxLatency: maxLatency}
+}
+
+func (a *aimd) Update(limit float64, s LimitSample) float64 {
+	if s.Overload || (a.maxLatency > 0 && s.RTT > a.maxLatency) {
+		if s.Started.Before(a.decreased) {
+			return limit
+		}
+		a.decreased = s.Started.Add(s.RTT)
+		return limit * a.ratio
+	}
+	if float64(s.InFlight)*2 >= limit {
+		return limit + 1
+	}
+	return limit
+}
+
+// minRTTWindow is the length of a window which Gradient tracks lowest RTT in
+const minRTTWindow = time.Minute
+
+type gradient struct {
+	tolerance float64
+	smoothing float64
+	// lowest RTT of current and previous window
+	minRTT, prevMinRTT time.Duration
+	windowEnd          time.Time
+}
+
+// Gradient creates a LimitAlgorithm which adjusts limit by latency gradient, like
+// TCP Vegas
+//
+// It tracks lowest RTT of recent one or two minutes as latency without load, so it
+// follows if the latency rises permanently. The gradient is
+// tolerance*minRTT/RTT clamped to [0.5, 1], so limit shrinks when RTT is more
+// than tolerance (default to 1.5 if < 1) times of minRTT, and grows by sqrt(limit)
+// (as allowed queueing) otherwise. Change is smoothed by smoothing (default to
+// 0.2 if not in (0, 1]). Limit is halved on overload.
+func Gradient(tolerance, smoothing float64) LimitAlgorithm {
+	if tolerance < 1 {
+		tolerance = 1.5
+	}
+	if smoothing <= 0 || smoothing > 1 {
+		smoothing = 0.2
+	}
+	return &gradient{tolerance: tolerance, smoothing: smoothing}
+}
+
+func (g *gradient) Update(limit float64, s LimitSample) float64 {
+	if s.Overload {
+		return limit / 2
+	}
+	if s.RTT <= 0 {
+		return limit
+	}
+
+	grad := math.Max(0.5, math.Min(1, g.tolerance*float64(g.baseline(s))/float64(s.RTT)))
+	next := limit*grad + math.Sqrt(limit)
+	if next > limit && float64(s.InFlight)*2 < limit {
+		// not utilized, no reason to grow
+		return limit
+	}
+	return limit*(1-g.smoothing) + next*g.smoothing
+}
+
+// baseline updates windows with s, and returns lowest RTT of them
+func (g *gradient) baseline(s LimitSample) time.Duration {
+	now := s.Started.Add(s.RTT)
+	if !now.Before(g.windowEnd) {
+		g.prevMinRTT, g.minRTT = g.minRTT, 0
+		if now.Sub(g.windowEnd) >= minRTTWindow {
+			// idle for whole window, previous one is too old
+			g.prevMinRTT = 0
+		}
+		g.windowEnd = now.Add(minRTTWindow)
+	}
+	if g.minRTT == 0 || s.RTT < g.minRTT {
+		g.minRTT = s.RTT
+	}
+
+	if g.prevMinRTT > 0 && g.prevMinRTT < g.minRTT {
+		return g.prevMinRTT
+	}
+	return g.minRTT
+}
+
+// AdaptiveLimiterConfig configures an AdaptiveLimiter
+//
+// Zero value of each field means default value, which is documented below.
+type AdaptiveLimiterConfig struct {
+	// Initial limit, default to 10.
+	Initial int
+	// Lower bound of limit, default to 1.
+	Min int
+	// Upper bound of limit, default to 1000.
+	Max int
+	// Computes the limit, default to AIMD(0.9, 0). Create one for each
+	// AdaptiveLimiter, as it keeps state.
+	Algorithm LimitAlgorithm
+	// Reports whether err means the backend is overloaded, default to err != nil.
+	IsOverload func(err error) bool
+}
+
+// AdaptiveLimiter limits concurrent executions like Bulkhead, but adjusts the
+// limit by latency and errors
+//
+// Calls exceeding the limit are rejected with ErrFull at once, so backend is not
+// overloaded by queued calls. Combine it with RetryWithBackoff if you need.
+//
+//    lim := NewAdaptiveLimiter(AdaptiveLimiterConfig{
+//        Algorithm: Gradient(2, 0.2),
+//    })
+//    err := lim.Run(callBackend)
+type AdaptiveLimiter struct {
+	lock     sync.Mutex
+	cfg      AdaptiveLimiterConfig
+	o        *options
+	limit    float64
+	inFlight int
+}
+
+// NewAdaptiveLimiter creates an AdaptiveLimiter
+//
+// Supported options: WithClock, WithObserver, WithName. Rejected calls are
+// reported as Dropped.
+func NewAdaptiveLimiter(cfg AdaptiveLimiterConfig, opts ...Option) (ret *AdaptiveLimiter) {
+	if cfg.Min < 1 {
+		cfg.Min = 1
+	}
+	if cfg.Max <= 0 {
+		cfg.Max = 1000
+	}
+	if cfg.Max < cfg.Min {
+		cfg.Max = cfg.Min
+	}
+	if cfg.Initial <= 0 {
+		cfg.Initial = 10
+	}
+	if cfg.Algorithm == nil {
+		cfg.Algorithm = AIMD(0.9, 0)
+	}
+	if cfg.IsOverload == nil {
+		cfg.IsOverload = func(err error) bool { return err != nil }
+	}
+
+	ret = &AdaptiveLimiter{
+		cfg: cfg,
+		o:   newOptions(opts),
+	}
+	ret.limit = ret.clamp(float64(cfg.Initial))
+	return
+}
+
+func (l *AdaptiveLimiter) clamp(limit float64) float64 {
+	return math.Max(float64(l.cfg.Min), math.Min(float64(l.cfg.Max), limit))
+}
+
+// Limit returns current concurrency limit
+func (l *AdaptiveLimiter) Limit() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return int(l.limit)
+}
+
+// InFlight returns number of running executions
+func (l *AdaptiveLimiter) InFlight() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.inFlight
+}
+
+// Run calls f if the limit allows, or returns ErrFull
+func (l *AdaptiveLimiter) Run(f func() error) (err error) {
+	idx := l.o.next()
+	l.lock.Lock()
+	if l.inFlight >= int(l.limit) {
+		l.lock.Unlock()
+		l.o.dropped(idx)
+		return ErrFull
+	}
+	l.inFlight++
+	inFlight := l.inFlight
+	l.lock.Unlock()
+
+	begin := l.o.clock.Now()
+	err = l.o.observe(idx, f)
+	s := LimitSample{
+		Started:  begin,
+		RTT:      l.o.clock.Now().Sub(begin),
+		InFlight: inFlight,
+		Overload: err != nil && l.cfg.IsOverload(err),
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.inFlight--
+	l.limit = l.clamp(l.cfg.Algorithm.Update(l.limit, s))
+	return
+}
+
+// Wrap creates a function which calls f through the limiter
+//
+// Functions wrapped by same AdaptiveLimiter share the same limit.
+func (l *AdaptiveLimiter) Wrap(f func() error) func() error {
+	return func() error {
+		return l.Run(f)
+	}
+}
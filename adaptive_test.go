@@ -0,0 +1,150 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+func TestAIMD(t *testing.T) {
+	cases := []struct {
+		name   string
+		s      LimitSample
+		expect float64
+	}{
+		{name: "grow", s: LimitSample{RTT: time.Millisecond, InFlight: 5}, expect: 11},
+		{name: "not utilized", s: LimitSample{RTT: time.Millisecond, InFlight: 4}, expect: 10},
+		{name: "overload", s: LimitSample{RTT: time.Millisecond, InFlight: 10, Overload: true}, expect: 5},
+		{name: "slow", s: LimitSample{RTT: 2 * time.Second, InFlight: 10}, expect: 5},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a := AIMD(0.5, time.Second)
+			if actual := a.Update(10, c.s); actual != c.expect {
+				t.Fatalf("expected %v, got %v", c.expect, actual)
+			}
+		})
+	}
+}
+
+func TestAIMDOncePerRTT(t *testing.T) {
+	a := AIMD(0.5, 0)
+	begin := time.Now()
+	sample := func(started time.Duration) LimitSample {
+		return LimitSample{Started: begin.Add(started), RTT: time.Second, Overload: true}
+	}
+
+	limit := a.Update(10, sample(0))
+	if limit != 5 {
+		t.Fatal("unexpected limit: ", limit)
+	}
+	// started before last decrease, same outage
+	if limit = a.Update(limit, sample(500*time.Millisecond)); limit != 5 {
+		t.Fatal("unexpected limit: ", limit)
+	}
+	if limit = a.Update(limit, sample(time.Second)); limit != 2.5 {
+		t.Fatal("unexpected limit: ", limit)
+	}
+}
+
+func TestGradientBaselineRises(t *testing.T) {
+	g := Gradient(1.5, 1)
+	begin := time.Now()
+	sample := func(at time.Duration, rtt time.Duration) LimitSample {
+		return LimitSample{Started: begin.Add(at), RTT: rtt, InFlight: 100}
+	}
+
+	limit := g.Update(16, sample(0, 10*time.Millisecond))
+	// latency rises permanently, limit shrinks until old baseline expires
+	limit = g.Update(limit, sample(time.Minute, 40*time.Millisecond))
+	if limit >= 16 {
+		t.Fatal("expected limit to shrink, got ", limit)
+	}
+	prev := g.Update(limit, sample(2*time.Minute, 40*time.Millisecond))
+	if limit = g.Update(prev, sample(3*time.Minute, 40*time.Millisecond)); limit <= prev {
+		t.Fatalf("expected limit to grow from %v, got %v", prev, limit)
+	}
+}
+
+func TestGradient(t *testing.T) {
+	g := Gradient(1, 1)
+	limit := 16.0
+
+	// first sample sets minRTT, grows by sqrt(limit)
+	if limit = g.Update(limit, LimitSample{RTT: 10 * time.Millisecond, InFlight: 16}); limit != 20 {
+		t.Fatal("unexpected limit: ", limit)
+	}
+	// not utilized
+	if l := g.Update(limit, LimitSample{RTT: 10 * time.Millisecond, InFlight: 1}); l != limit {
+		t.Fatal("unexpected limit: ", l)
+	}
+	// latency doubled: 25*0.5 + 5
+	if limit = g.Update(25, LimitSample{RTT: 20 * time.Millisecond, InFlight: 25}); limit != 17.5 {
+		t.Fatal("unexpected limit: ", limit)
+	}
+	if limit = g.Update(limit, LimitSample{Overload: true}); limit != 8.75 {
+		t.Fatal("unexpected limit: ", limit)
+	}
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	theErr := errors.New("")
+	l := NewAdaptiveLimiter(AdaptiveLimiterConfig{
+		Initial:   2,
+		Max:       3,
+		Algorithm: AIMD(0.5, time.Second),
+	}, WithClock(c))
+	if l.Limit() != 2 {
+		t.Fatal("unexpected initial limit: ", l.Limit())
+	}
+
+	release := make(chan error)
+	started := make(chan struct{})
+	done := make(chan error)
+	f := l.Wrap(func() error {
+		started <- struct{}{}
+		return <-release
+	})
+	for i := 0; i < 2; i++ {
+		go func() { done <- f() }()
+		<-started
+	}
+	if l.InFlight() != 2 {
+		t.Fatal("unexpected in-flight: ", l.InFlight())
+	}
+	if err := l.Run(func() error { return nil }); err != ErrFull {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	// healthy, grows to max
+	release <- nil
+	<-done
+	release <- nil
+	<-done
+	if l.Limit() != 3 {
+		t.Fatal("expected limit to grow to 3, got ", l.Limit())
+	}
+
+	// overload
+	l.Run(func() error { return theErr })
+	if l.Limit() != 1 {
+		t.Fatal("expected limit to shrink to 1, got ", l.Limit())
+	}
+
+	// latency spike, but bounded by min
+	l.Run(func() error {
+		c.Advance(2 * time.Second)
+		return nil
+	})
+	if l.Limit() != 1 || l.InFlight() != 0 {
+		t.Fatalf("unexpected state: %d, %d", l.Limit(), l.InFlight())
+	}
+}
@@ -10,11 +10,20 @@ import "time"
 //
 // It will blocked until dur is reached and f() is returned.
 func RunAtLeast(dur time.Duration, f func() error) func() error {
+	return RunAtLeastOpts(dur, f)
+}
+
+// RunAtLeastOpts is identical to RunAtLeast, but accepts options
+//
+// Supported options: WithClock, WithObserver, WithName
+func RunAtLeastOpts(dur time.Duration, f func() error, opts ...Option) func() error {
+	o := newOptions(opts)
 	return func() (err error) {
-		begin := time.Now()
-		err = f()
-		if d := time.Since(begin); d <= dur {
-			time.Sleep(dur - d)
+		idx := o.next()
+		begin := o.clock.Now()
+		err = o.observe(idx, f)
+		if d := o.clock.Now().Sub(begin); d <= dur {
+			o.sleep(idx, dur-d)
 		}
 		return
 	}
@@ -28,11 +37,20 @@ func RunAtLeast(dur time.Duration, f func() error) func() error {
 //   x() // costs 0.1s if this attempt failed
 //   x() // costs   1s if thst attempt succeeded
 func RunSuccessAtLeast(dur time.Duration, f func() error) func() error {
+	return RunSuccessAtLeastOpts(dur, f)
+}
+
+// RunSuccessAtLeastOpts is identical to RunSuccessAtLeast, but accepts options
+//
+// Supported options: WithClock, WithObserver, WithName
+func RunSuccessAtLeastOpts(dur time.Duration, f func() error, opts ...Option) func() error {
+	o := newOptions(opts)
 	return func() (err error) {
-		begin := time.Now()
-		err = f()
-		if d := time.Since(begin); err == nil && d <= dur {
-			time.Sleep(dur - d)
+		idx := o.next()
+		begin := o.clock.Now()
+		err = o.observe(idx, f)
+		if d := o.clock.Now().Sub(begin); err == nil && d <= dur {
+			o.sleep(idx, dur-d)
 		}
 		return
 	}
@@ -46,11 +64,20 @@ func RunSuccessAtLeast(dur time.Duration, f func() error) func() error {
 //   x() // costs   1s if this attempt failed
 //   x() // costs 0.1s if thst attempt succeeded
 func RunFailedAtLeast(dur time.Duration, f func() error) func() error {
+	return RunFailedAtLeastOpts(dur, f)
+}
+
+// RunFailedAtLeastOpts is identical to RunFailedAtLeast, but accepts options
+//
+// Supported options: WithClock, WithObserver, WithName
+func RunFailedAtLeastOpts(dur time.Duration, f func() error, opts ...Option) func() error {
+	o := newOptions(opts)
 	return func() (err error) {
-		begin := time.Now()
-		err = f()
-		if d := time.Since(begin); err != nil && d <= dur {
-			time.Sleep(dur - d)
+		idx := o.next()
+		begin := o.clock.Now()
+		err = o.observe(idx, f)
+		if d := o.clock.Now().Sub(begin); err != nil && d <= dur {
+			o.sleep(idx, dur-d)
 		}
 		return
 	}
@@ -8,6 +8,8 @@ import (
 	"errors"
 	"testing"
 	"time"
+
+	"github.com/raohwork/routines/routinestest"
 )
 
 func TestRunAtLeast(t *testing.T) {
@@ -82,3 +84,53 @@ func TestRunCondAtLeast(t *testing.T) {
 		}
 	}
 }
+
+func TestRunAtLeastOpts(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	theErr := errors.New("")
+	cases := []struct {
+		name  string
+		f     func(time.Duration, func() error, ...Option) func() error
+		err   error
+		sleep bool
+	}{
+		{name: "any-ok", f: RunAtLeastOpts, err: nil, sleep: true},
+		{name: "any-fail", f: RunAtLeastOpts, err: theErr, sleep: true},
+		{name: "success-ok", f: RunSuccessAtLeastOpts, err: nil, sleep: true},
+		{name: "success-fail", f: RunSuccessAtLeastOpts, err: theErr, sleep: false},
+		{name: "failed-ok", f: RunFailedAtLeastOpts, err: nil, sleep: false},
+		{name: "failed-fail", f: RunFailedAtLeastOpts, err: theErr, sleep: true},
+	}
+
+	for _, x := range cases {
+		t.Run(x.name, func(t *testing.T) {
+			f := x.f(time.Second, func() error {
+				// simulates f() costs 300ms
+				c.Advance(300 * time.Millisecond)
+				return x.err
+			}, WithClock(c))
+
+			done := make(chan error)
+			go func() { done <- f() }()
+
+			if !x.sleep {
+				if err := <-done; err != x.err {
+					t.Fatal("unexpected error: ", err)
+				}
+				return
+			}
+
+			c.BlockUntil(1)
+			c.Advance(699 * time.Millisecond)
+			select {
+			case <-done:
+				t.Fatal("returned before 1s")
+			default:
+			}
+			c.Advance(time.Millisecond)
+			if err := <-done; err != x.err {
+				t.Fatal("unexpected error: ", err)
+			}
+		})
+	}
+}
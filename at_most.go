@@ -33,16 +33,27 @@ import (
 //    x() // blocks 1s
 //    x() // blocks 1s
 func OnceAtMost(dur time.Duration, f func() error) func() error {
+	return OnceAtMostOpts(dur, f)
+}
+
+// OnceAtMostOpts is identical to OnceAtMost, but accepts options
+//
+// Supported options: WithClock, WithObserver, WithName
+func OnceAtMostOpts(dur time.Duration, f func() error, opts ...Option) func() error {
+	o := newOptions(opts)
 	lock := new(sync.Mutex)
-	last := time.Now().Add(0 - dur)
+	var last time.Time // zero time, so first call is never blocked
 	return func() error {
+		idx := o.next()
+		enter := o.clock.Now()
 		lock.Lock()
 		defer lock.Unlock()
-		if d := time.Since(last); d <= dur {
-			time.Sleep(dur - d)
+		if d := o.clock.Now().Sub(last); d <= dur {
+			o.clock.Sleep(dur - d)
 		}
-		last = time.Now()
-		return f()
+		o.waited(idx, o.clock.Now().Sub(enter))
+		last = o.clock.Now()
+		return o.observe(idx, f)
 	}
 }
 
@@ -61,17 +72,28 @@ func OnceAtMost(dur time.Duration, f func() error) func() error {
 //    * another "test" at 0.2s (0.1s after previous "test")
 //    * another "test" at 1.2s (1s after previous "test")
 func OnceSuccessAtMost(dur time.Duration, f func() error) func() error {
+	return OnceSuccessAtMostOpts(dur, f)
+}
+
+// OnceSuccessAtMostOpts is identical to OnceSuccessAtMost, but accepts options
+//
+// Supported options: WithClock, WithObserver, WithName
+func OnceSuccessAtMostOpts(dur time.Duration, f func() error, opts ...Option) func() error {
+	o := newOptions(opts)
 	lock := new(sync.Mutex)
-	last := time.Now().Add(0 - dur)
+	var last time.Time // zero time, so first call is never blocked
 	return func() error {
+		idx := o.next()
+		enter := o.clock.Now()
 		lock.Lock()
 		defer lock.Unlock()
-		if d := time.Since(last); d <= dur {
-			time.Sleep(dur - d)
+		if d := o.clock.Now().Sub(last); d <= dur {
+			o.clock.Sleep(dur - d)
 		}
+		o.waited(idx, o.clock.Now().Sub(enter))
 
-		now := time.Now()
-		ret := f()
+		now := o.clock.Now()
+		ret := o.observe(idx, f)
 		if ret == nil {
 			last = now
 		}
@@ -90,23 +112,34 @@ func OnceSuccessAtMost(dur time.Duration, f func() error) func() error {
 //    time.Sleep(time.Second)
 //    go x() // this should be executed and print "test"
 func OnceWithin(dur time.Duration, f func() error) func() error {
+	return OnceWithinOpts(dur, f)
+}
+
+// OnceWithinOpts is identical to OnceWithin, but accepts options
+//
+// Supported options: WithClock, WithObserver, WithName
+func OnceWithinOpts(dur time.Duration, f func() error, opts ...Option) func() error {
+	o := newOptions(opts)
 	lock := new(sync.RWMutex)
-	last := time.Now().Add(0 - dur)
+	var last time.Time // zero time, so first call is never blocked
 	return func() error {
+		idx := o.next()
 		lock.RLock()
-		if d := time.Since(last); d <= dur {
+		if d := o.clock.Now().Sub(last); d <= dur {
 			lock.RUnlock()
+			o.dropped(idx)
 			return nil
 		}
 		lock.RUnlock()
 
 		lock.Lock()
 		defer lock.Unlock()
-		if d := time.Since(last); d <= dur {
+		if d := o.clock.Now().Sub(last); d <= dur {
+			o.dropped(idx)
 			return nil
 		}
-		last = time.Now()
-		return f()
+		last = o.clock.Now()
+		return o.observe(idx, f)
 	}
 }
 
@@ -121,24 +154,35 @@ func OnceWithin(dur time.Duration, f func() error) func() error {
 //    time.Sleep(time.Second)
 //    go x() // f is executed
 func OnceSuccessWithin(dur time.Duration, f func() error) func() error {
+	return OnceSuccessWithinOpts(dur, f)
+}
+
+// OnceSuccessWithinOpts is identical to OnceSuccessWithin, but accepts options
+//
+// Supported options: WithClock, WithObserver, WithName
+func OnceSuccessWithinOpts(dur time.Duration, f func() error, opts ...Option) func() error {
+	o := newOptions(opts)
 	lock := new(sync.RWMutex)
-	last := time.Now().Add(0 - dur)
+	var last time.Time // zero time, so first call is never blocked
 	return func() error {
+		idx := o.next()
 		lock.RLock()
-		if d := time.Since(last); d <= dur {
+		if d := o.clock.Now().Sub(last); d <= dur {
 			lock.RUnlock()
+			o.dropped(idx)
 			return nil
 		}
 		lock.RUnlock()
 
 		lock.Lock()
 		defer lock.Unlock()
-		if d := time.Since(last); d <= dur {
+		if d := o.clock.Now().Sub(last); d <= dur {
+			o.dropped(idx)
 			return nil
 		}
 
-		now := time.Now()
-		ret := f()
+		now := o.clock.Now()
+		ret := o.observe(idx, f)
 		if ret == nil {
 			last = now
 		}
@@ -8,6 +8,8 @@ import (
 	"errors"
 	"testing"
 	"time"
+
+	"github.com/raohwork/routines/routinestest"
 )
 
 func TestOnceAtMost(t *testing.T) {
@@ -145,3 +147,61 @@ func TestOnceSuccessWithin(t *testing.T) {
 		t.Fatalf("expected run twice, got %d", a)
 	}
 }
+
+func TestOnceAtMostOpts(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	cnt := 0
+	f := OnceAtMostOpts(time.Second, func() error {
+		cnt++
+		return nil
+	}, WithClock(c))
+
+	f() // first call is not blocked
+	done := make(chan error)
+	go func() { done <- f() }()
+
+	c.BlockUntil(1)
+	if cnt != 1 {
+		t.Fatalf("expected run once, got %d", cnt)
+	}
+	c.Advance(time.Second)
+	<-done
+	if cnt != 2 {
+		t.Fatalf("expected run twice, got %d", cnt)
+	}
+}
+
+func TestOnceWithinOpts(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	var e error
+	cnt := [2]int{}
+	funcs := []func() error{
+		OnceWithinOpts(time.Second, func() error {
+			cnt[0]++
+			return e
+		}, WithClock(c)),
+		OnceSuccessWithinOpts(time.Second, func() error {
+			cnt[1]++
+			return e
+		}, WithClock(c)),
+	}
+
+	e = errors.New("")
+	for _, f := range funcs {
+		f()
+		f()
+	}
+	if cnt != [2]int{1, 2} {
+		t.Fatalf("unexpected result: %v", cnt)
+	}
+
+	e = nil
+	c.Advance(time.Second + 1)
+	for _, f := range funcs {
+		f()
+		f()
+	}
+	if cnt != [2]int{2, 3} {
+		t.Fatalf("unexpected result: %v", cnt)
+	}
+}
@@ -0,0 +1,210 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+const maxDuration = time.Duration(math.MaxInt64)
+
+// Backoff computes how long to wait before next attempt
+//
+// idx is the index (zero-based) of failed attempt, and prev is the delay returned
+// by previous call to Next (0 for first call). Passing prev back makes it possible
+// to write stateful strategy like DecorrelatedJitter as a stateless value, so a
+// Backoff can be shared by many retry loops.
+type Backoff interface {
+	Next(idx uint64, prev time.Duration) time.Duration
+}
+
+// BackoffFunc is an adapter to use ordinary function as Backoff
+type BackoffFunc func(idx uint64, prev time.Duration) time.Duration
+
+// Next implements Backoff
+func (f BackoffFunc) Next(idx uint64, prev time.Duration) time.Duration {
+	return f(idx, prev)
+}
+
+func capOf(max time.Duration) time.Duration {
+	if max <= 0 {
+		return maxDuration
+	}
+	return max
+}
+
+// ConstantBackoff waits d between every attempts
+func ConstantBackoff(d time.Duration) Backoff {
+	return BackoffFunc(func(_ uint64, _ time.Duration) time.Duration {
+		return d
+	})
+}
+
+// LinearBackoff waits base, base+step, base+2*step ... but no more than max
+//
+// max <= 0 means no limit.
+func LinearBackoff(base, step, max time.Duration) Backoff {
+	max = capOf(max)
+	return BackoffFunc(func(idx uint64, _ time.Duration) time.Duration {
+		if base >= max || (step > 0 && idx > uint64((max-base)/step)) {
+			return max
+		}
+		if step <= 0 {
+			return base
+		}
+		return base + time.Duration(idx)*step
+	})
+}
+
+// ExponentialBackoff waits base, 2*base, 4*base ... but no more than max
+//
+// max <= 0 means no limit. base <= 0 is returned as-is as it never grows.
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	max = capOf(max)
+	return BackoffFunc(func(idx uint64, _ time.Duration) time.Duration {
+		if base <= 0 {
+			return base
+		}
+		d := base
+		for x := uint64(0); x < idx; x++ {
+			if d > max/2 {
+				return max
+			}
+			d *= 2
+		}
+		if d > max {
+			return max
+		}
+		return d
+	})
+}
+
+// FibonacciBackoff waits base, base, 2*base, 3*base, 5*base ... but no more than max
+//
+// max <= 0 means no limit. base <= 0 is returned as-is as it never grows.
+func FibonacciBackoff(base, max time.Duration) Backoff {
+	max = capOf(max)
+	return BackoffFunc(func(idx uint64, _ time.Duration) time.Duration {
+		if base <= 0 {
+			return base
+		}
+		a, b := base, base
+		for x := uint64(0); x < idx; x++ {
+			if b >= max {
+				return max
+			}
+			next := maxDuration
+			if a <= maxDuration-b {
+				next = a + b
+			}
+			a, b = b, next
+		}
+		if a > max {
+			return max
+		}
+		return a
+	})
+}
+
+// randDuration returns random duration in [0, d]
+func randDuration(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	if d == maxDuration {
+		return time.Duration(rand.Int63())
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// FullJitter randomizes delay computed by b to [0, delay]
+//
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+func FullJitter(b Backoff) Backoff {
+	return BackoffFunc(func(idx uint64, prev time.Duration) time.Duration {
+		return randDuration(b.Next(idx, prev))
+	})
+}
+
+// EqualJitter randomizes delay computed by b to [delay/2, delay]
+//
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+func EqualJitter(b Backoff) Backoff {
+	return BackoffFunc(func(idx uint64, prev time.Duration) time.Duration {
+		d := b.Next(idx, prev)
+		return d - d/2 + randDuration(d/2)
+	})
+}
+
+// DecorrelatedJitter waits random duration in [base, prev*3], but no more than max
+//
+// max <= 0 means no limit.
+//
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+func DecorrelatedJitter(base, max time.Duration) Backoff {
+	max = capOf(max)
+	return BackoffFunc(func(_ uint64, prev time.Duration) time.Duration {
+		if prev < base {
+			prev = base
+		}
+		upper := max
+		if prev <= max/3 {
+			upper = prev * 3
+		}
+		if upper <= base {
+			return upper
+		}
+		return base + randDuration(upper-base)
+	})
+}
+
+// withBackoff sleeps before every attempt except first one
+func withBackoff(b Backoff, f func() error, o *options) func() error {
+	var prev time.Duration
+	bud := o.newBudget()
+	g := o.classify(f)
+	return Recorded(func(idx uint64) error {
+		if idx > 0 {
+			prev = b.Next(idx-1, prev)
+			if bud.exhausted(prev) || !bud.sleep(idx, prev) {
+				return bud.err()
+			}
+		}
+		return bud.attempt(idx, g)
+	})
+}
+
+// RetryWithBackoff is identical to Retry, but waits between attempts
+//
+// The delay is computed by b, and is applied after the error is consumed from err.
+//
+// Supported options: WithClock, WithObserver, WithName, WithClassifier,
+// WithMaxElapsed, WithDeadline, WithContext
+//
+//    // waits 100ms, 200ms, 400ms ... 10s, 10s, 10s
+//    ch := RetryWithBackoff(ExponentialBackoff(100*time.Millisecond, 10*time.Second), f)
+func RetryWithBackoff(b Backoff, f func() error, opts ...Option) (err chan error) {
+	return Retry(withBackoff(b, f, newOptions(opts)))
+}
+
+// TriesAtMostWithBackoff is identical to TriesAtMost, but waits between attempts
+//
+// It does not wait after last attempt.
+//
+// Supported options: WithClock, WithObserver, WithName, WithClassifier,
+// WithMaxElapsed, WithDeadline, WithContext
+func TriesAtMostWithBackoff(n uint64, b Backoff, f func() error, opts ...Option) (err chan error) {
+	return TriesAtMost(n, withBackoff(b, f, newOptions(opts)))
+}
+
+// TryAtMostWithBackoff is identical to TryAtMost, but waits between attempts
+//
+// Supported options: WithClock, WithObserver, WithName, WithClassifier,
+// WithMaxElapsed, WithDeadline, WithContext
+func TryAtMostWithBackoff(n uint64, b Backoff, f func() error, opts ...Option) (err error) {
+	return TryAtMost(n, withBackoff(b, f, newOptions(opts)))
+}
@@ -0,0 +1,47 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"fmt"
+	"time"
+)
+
+func ExampleExponentialBackoff() {
+	b := ExponentialBackoff(100*time.Millisecond, time.Second)
+	prev := time.Duration(0)
+	for idx := uint64(0); idx < 6; idx++ {
+		prev = b.Next(idx, prev)
+		fmt.Println(prev)
+	}
+
+	// output: 100ms
+	// 200ms
+	// 400ms
+	// 800ms
+	// 1s
+	// 1s
+}
+
+func ExampleRetryWithBackoff() {
+	f := Recorded(func(idx uint64) error {
+		if idx <= 1 {
+			return fmt.Errorf("attempt #%d failed", idx)
+		}
+		return nil
+	})
+
+	// spaces attempts by 1ms, 2ms, 4ms ... but no more than 10ms, and randomize
+	// them to prevent all clients from retrying at the same time
+	b := FullJitter(ExponentialBackoff(time.Millisecond, 10*time.Millisecond))
+	for e := range RetryWithBackoff(b, f) {
+		fmt.Println(e)
+	}
+	fmt.Println("done")
+
+	// output: attempt #0 failed
+	// attempt #1 failed
+	// done
+}
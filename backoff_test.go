@@ -0,0 +1,179 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoffSequence(t *testing.T) {
+	ms := time.Millisecond
+	cases := []struct {
+		name   string
+		b      Backoff
+		expect []time.Duration
+	}{
+		{
+			name:   "constant",
+			b:      ConstantBackoff(3 * ms),
+			expect: []time.Duration{3 * ms, 3 * ms, 3 * ms},
+		},
+		{
+			name:   "linear",
+			b:      LinearBackoff(ms, 2*ms, 6*ms),
+			expect: []time.Duration{ms, 3 * ms, 5 * ms, 6 * ms, 6 * ms},
+		},
+		{
+			name:   "exponential",
+			b:      ExponentialBackoff(ms, 10*ms),
+			expect: []time.Duration{ms, 2 * ms, 4 * ms, 8 * ms, 10 * ms, 10 * ms},
+		},
+		{
+			name:   "fibonacci",
+			b:      FibonacciBackoff(ms, 10*ms),
+			expect: []time.Duration{ms, ms, 2 * ms, 3 * ms, 5 * ms, 8 * ms, 10 * ms},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			prev := time.Duration(0)
+			for idx, expect := range c.expect {
+				prev = c.b.Next(uint64(idx), prev)
+				if prev != expect {
+					t.Fatalf("#%d: expected %v, got %v", idx, expect, prev)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffNoOverflow(t *testing.T) {
+	funcs := []Backoff{
+		LinearBackoff(time.Second, time.Hour, 0),
+		ExponentialBackoff(time.Second, 0),
+		FibonacciBackoff(time.Second, 0),
+	}
+
+	for idx, b := range funcs {
+		if d := b.Next(1<<62, 0); d != maxDuration {
+			t.Errorf("#%d: expected %v, got %v", idx, maxDuration, d)
+		}
+	}
+
+	// base never grows, should not loop until idx
+	zeros := []Backoff{
+		ExponentialBackoff(0, time.Second),
+		FibonacciBackoff(0, time.Second),
+		ExponentialBackoff(-time.Second, 0),
+		FibonacciBackoff(-time.Second, 0),
+	}
+	for idx, b := range zeros {
+		if d := b.Next(1<<62, 0); d > 0 {
+			t.Errorf("#%d: expected no delay, got %v", idx, d)
+		}
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	base := ConstantBackoff(100 * time.Millisecond)
+	cases := []struct {
+		name     string
+		b        Backoff
+		min, max time.Duration
+	}{
+		{
+			name: "full",
+			b:    FullJitter(base),
+			min:  0,
+			max:  100 * time.Millisecond,
+		},
+		{
+			name: "equal",
+			b:    EqualJitter(base),
+			min:  50 * time.Millisecond,
+			max:  100 * time.Millisecond,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for x := uint64(0); x < 100; x++ {
+				d := c.b.Next(x, 0)
+				if d < c.min || d > c.max {
+					t.Fatalf("expected in [%v, %v], got %v", c.min, c.max, d)
+				}
+			}
+		})
+	}
+}
+
+func TestDecorrelatedJitter(t *testing.T) {
+	base, max := 10*time.Millisecond, 100*time.Millisecond
+	b := DecorrelatedJitter(base, max)
+	prev := time.Duration(0)
+	for x := uint64(0); x < 100; x++ {
+		upper := prev * 3
+		if upper < base*3 {
+			upper = base * 3
+		}
+		if upper > max {
+			upper = max
+		}
+
+		prev = b.Next(x, prev)
+		if prev < base || prev > upper {
+			t.Fatalf("#%d: expected in [%v, %v], got %v", x, base, upper, prev)
+		}
+	}
+}
+
+func TestTriesAtMostWithBackoff(t *testing.T) {
+	theErr := errors.New("the error")
+	delays := []time.Duration{}
+	b := BackoffFunc(func(idx uint64, prev time.Duration) time.Duration {
+		d := time.Duration(idx+1) * time.Millisecond
+		delays = append(delays, d)
+		return d
+	})
+
+	cnt := 0
+	begin := time.Now()
+	for err := range TriesAtMostWithBackoff(3, b, func() error { return theErr }) {
+		cnt++
+		if err != theErr {
+			t.Fatal("unexpected error: ", err)
+		}
+	}
+	actual := time.Since(begin)
+
+	if cnt != 3 {
+		t.Fatalf("expected to failed 3 times, got %d", cnt)
+	}
+	// no delay after last attempt
+	if l := len(delays); l != 2 {
+		t.Fatalf("expected to wait 2 times, got %d", l)
+	}
+	if expect := 3 * time.Millisecond; actual < expect {
+		t.Errorf("expected at least %v, got %v", expect, actual)
+	}
+}
+
+func TestTryAtMostWithBackoff(t *testing.T) {
+	theErr := errors.New("the error")
+	f := Recorded(func(idx uint64) error {
+		if idx >= 2 {
+			return nil
+		}
+		return theErr
+	})
+
+	err := TryAtMostWithBackoff(3, ConstantBackoff(time.Millisecond), f)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+}
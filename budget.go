@@ -0,0 +1,123 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBudgetExhausted is wrapped in the error returned by Retry-family functions
+// when the time budget (see WithMaxElapsed) is exhausted
+//
+// The error also wraps last error returned by f, and is a PermanentError.
+var ErrBudgetExhausted = errors.New("Retry: time budget exhausted")
+
+// WithMaxElapsed limits total elapsed time of retrying, d <= 0 means no limit
+//
+// Time is counted from the call to Retry-family function. An attempt (and
+// waiting before it) which would start after the budget is not executed.
+func WithMaxElapsed(d time.Duration) Option {
+	return func(o *options) {
+		o.maxElapsed = d
+	}
+}
+
+// WithDeadline is identical to WithMaxElapsed, but uses an absolute time
+func WithDeadline(t time.Time) Option {
+	return func(o *options) {
+		o.deadline = t
+	}
+}
+
+// WithContext stops retrying when ctx is done, and uses its deadline as budget
+//
+// If ctx is cancelled, the returned error wraps context.Canceled instead of
+// ErrBudgetExhausted.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// budget tracks time budget of a retry session
+type budget struct {
+	o        *options
+	deadline time.Time // zero means no limit
+	last     error
+}
+
+func (o *options) newBudget() (ret *budget) {
+	ret = &budget{o: o, deadline: o.deadline}
+	if o.maxElapsed > 0 {
+		t := o.clock.Now().Add(o.maxElapsed)
+		if ret.deadline.IsZero() || t.Before(ret.deadline) {
+			ret.deadline = t
+		}
+	}
+	if o.ctx != nil {
+		if t, ok := o.ctx.Deadline(); ok && (ret.deadline.IsZero() || t.Before(ret.deadline)) {
+			ret.deadline = t
+		}
+	}
+	return
+}
+
+// exhausted reports whether an attempt starting after wait is out of budget
+func (b *budget) exhausted(wait time.Duration) bool {
+	if b.o.ctx != nil && b.o.ctx.Err() != nil {
+		return true
+	}
+	return !b.deadline.IsZero() && b.o.clock.Now().Add(wait).After(b.deadline)
+}
+
+func (b *budget) err() error {
+	reason := ErrBudgetExhausted
+	if b.o.ctx != nil && b.o.ctx.Err() == context.Canceled {
+		reason = context.Canceled
+	}
+	if b.last == nil {
+		return Permanent(reason)
+	}
+	return Permanent(fmt.Errorf("%w: %w", reason, b.last))
+}
+
+// sleep waits for d, returns false if ctx is done first
+func (b *budget) sleep(attempt uint64, d time.Duration) bool {
+	if b.o.ctx == nil {
+		b.o.sleep(attempt, d)
+		return true
+	}
+
+	timer, stop := newTimer(b.o.clock, d)
+	defer stop()
+	select {
+	case <-b.o.ctx.Done():
+		return false
+	case <-timer:
+		b.o.waited(attempt, d)
+		return true
+	}
+}
+
+// attempt runs f with Observer if the budget allows
+func (b *budget) attempt(idx uint64, f func() error) error {
+	if b.exhausted(0) {
+		return b.err()
+	}
+	b.last = b.o.observe(idx, f)
+	return b.last
+}
+
+// retried prepares f for Retry-family functions
+func (o *options) retried(f func() error) func() error {
+	b := o.newBudget()
+	g := o.classify(f)
+	return func() error {
+		return b.attempt(o.next(), g)
+	}
+}
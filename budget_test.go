@@ -0,0 +1,116 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+func TestBudget(t *testing.T) {
+	theErr := errors.New("the error")
+	now := time.Now()
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(time.Hour+2500*time.Millisecond))
+	defer cancel()
+
+	cases := []struct {
+		name string
+		opt  func(c *routinestest.Clock) Option
+	}{
+		{
+			name: "max elapsed",
+			opt:  func(*routinestest.Clock) Option { return WithMaxElapsed(2500 * time.Millisecond) },
+		},
+		{
+			name: "deadline",
+			opt:  func(c *routinestest.Clock) Option { return WithDeadline(c.Now().Add(2500 * time.Millisecond)) },
+		},
+		{
+			// fake clock is an hour later, so ctx is not done in real time
+			name: "context",
+			opt:  func(*routinestest.Clock) Option { return WithContext(ctx) },
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clock := routinestest.NewClock(now.Add(time.Hour))
+			cnt := 0
+			err := TryAtMostOpts(10, func() error {
+				cnt++
+				clock.Advance(time.Second)
+				return theErr
+			}, WithClock(clock), c.opt(clock))
+
+			if cnt != 3 {
+				t.Fatal("expected run 3 times, got ", cnt)
+			}
+			if !errors.Is(err, ErrBudgetExhausted) || !errors.Is(err, theErr) || !IsPermanent(err) {
+				t.Fatal("unexpected error: ", err)
+			}
+		})
+	}
+}
+
+func TestBudgetBackoff(t *testing.T) {
+	theErr := errors.New("the error")
+	c := routinestest.NewClock(time.Now())
+	cnt := 0
+	done := make(chan error)
+	go func() {
+		done <- TryAtMostWithBackoff(10, ConstantBackoff(time.Second), func() error {
+			cnt++
+			return theErr
+		}, WithClock(c), WithMaxElapsed(1500*time.Millisecond))
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	// stops without waiting, as next attempt would start at 2s
+	err := <-done
+	if cnt != 2 {
+		t.Fatal("expected run twice, got ", cnt)
+	}
+	if !errors.Is(err, ErrBudgetExhausted) || !errors.Is(err, theErr) {
+		t.Fatal("unexpected error: ", err)
+	}
+}
+
+func TestBudgetCancel(t *testing.T) {
+	theErr := errors.New("the error")
+	c := routinestest.NewClock(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	cnt := 0
+	done := make(chan error)
+	go func() {
+		done <- TryAtMostWithBackoff(10, ConstantBackoff(time.Second), func() error {
+			cnt++
+			return theErr
+		}, WithClock(c), WithContext(ctx))
+	}()
+
+	c.BlockUntil(1)
+	cancel()
+	err := <-done
+	if cnt != 1 {
+		t.Fatal("expected run once, got ", cnt)
+	}
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, theErr) || errors.Is(err, ErrBudgetExhausted) {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	cnt = 0
+	err = <-RetryOpts(func() error {
+		cnt++
+		return nil
+	}, WithContext(ctx))
+	if cnt != 0 || !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected result: %d, %v", cnt, err)
+	}
+}
@@ -0,0 +1,226 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrFull is returned by Bulkhead if no slot is free and the queue is full
+	ErrFull = errors.New("Bulkhead: no free slot")
+	// ErrQueueTimeout is returned by Bulkhead if it waits in the queue too long
+	ErrQueueTimeout = errors.New("Bulkhead: queue timeout")
+)
+
+// Bulkhead is a function which allows limited concurrent executions
+//
+// It is a generalization of StatefulFunc, which allows only one execution.
+// Callers exceeding the limit wait in a queue, see WithQueue and
+// WithQueueTimeout.
+type Bulkhead interface {
+	// number of running executions (and held locks)
+	InFlight() int
+	// number of callers waiting in the queue
+	Queued() int
+	// try to run the function now, return ErrFull immediately if no slot is free
+	TryRun() (err error)
+	// run this function, waits in the queue if no slot is free
+	Run() (err error)
+	// like Run, but gives up and returns ctx.Err() if ctx is done first
+	RunContext(ctx context.Context) (err error)
+	// blocks until a slot is free, and hold it instead of running the function
+	//
+	// Like StatefulFunc, not releasing it makes others wait forever. It ignores
+	// WithQueue and WithQueueTimeout, use TryLock or LockContext if you want to
+	// give up.
+	//
+	// It's safe to call release multiple times, only first time is executed.
+	Lock() (release func())
+	// try to hold a slot now, return ErrFull immediately if no slot is free
+	TryLock() (release func(), err error)
+	// waits in the queue like Run, and hold a slot instead of running the
+	// function, gives up and returns ctx.Err() if ctx is done first
+	LockContext(ctx context.Context) (release func(), err error)
+	// creates a function which runs f like Run, functions wrapped by same
+	// Bulkhead share the slots
+	Wrap(f func() error) func() error
+}
+
+// WithQueue limits number of callers waiting in the queue of Bulkhead, n < 0
+// means no limit (default)
+//
+// Callers exceeding the limit get ErrFull. WithQueue(0) means no waiting at all.
+func WithQueue(n int) Option {
+	return func(o *options) {
+		o.queueSize = n
+	}
+}
+
+// WithQueueTimeout limits time of waiting in the queue of Bulkhead, d <= 0 means
+// no limit (default)
+//
+// Callers waiting too long get ErrQueueTimeout.
+func WithQueueTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.queueTimeout = d
+	}
+}
+
+type bulkhead struct {
+	slots  chan struct{}
+	queued atomic.Int64
+	f      func() error
+	o      *options
+}
+
+// NewBulkhead creates a Bulkhead which allows at most n concurrent executions of
+// f, n < 1 is treated as 1
+//
+//    // at most 10 queries at the same time, others wait for at most 1s
+//    query := NewBulkhead(10, queryDB, WithQueueTimeout(time.Second))
+//    if err := query.Run(); err == ErrQueueTimeout {
+//        log.Print("database is busy")
+//    }
+//
+// Supported options: WithClock, WithObserver, WithName, WithQueue,
+// WithQueueTimeout. Rejected calls are reported as Dropped, and time waited in
+// the queue is reported as Waited.
+//
+// Use Bulkheaded if you want to limit other functions with same slots.
+func NewBulkhead(n int, f func() error, opts ...Option) Bulkhead {
+	if n < 1 {
+		n = 1
+	}
+	return &bulkhead{
+		slots: make(chan struct{}, n),
+		f:     f,
+		o:     newOptions(opts),
+	}
+}
+
+func (b *bulkhead) InFlight() int {
+	return len(b.slots)
+}
+
+func (b *bulkhead) Queued() int {
+	return int(b.queued.Load())
+}
+
+// acquire takes a slot, waits in the queue if needed
+//
+// Limits set by WithQueue and WithQueueTimeout are applied only if bounded is set.
+func (b *bulkhead) acquire(ctx context.Context, idx uint64, bounded bool) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return
+	default:
+	}
+
+	q := b.queued.Add(1)
+	defer b.queued.Add(-1)
+	if s := b.o.queueSize; bounded && s >= 0 && q > int64(s) {
+		b.o.dropped(idx)
+		return ErrFull
+	}
+
+	begin := b.o.clock.Now()
+	var timeout <-chan time.Time
+	if d := b.o.queueTimeout; bounded && d > 0 {
+		var stop func()
+		timeout, stop = newTimer(b.o.clock, d)
+		defer stop()
+	}
+	select {
+	case b.slots <- struct{}{}:
+		b.o.waited(idx, b.o.clock.Now().Sub(begin))
+		return
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = ErrQueueTimeout
+	}
+	b.o.dropped(idx)
+	return
+}
+
+// tryAcquire takes a slot if it is free, or returns ErrFull
+func (b *bulkhead) tryAcquire(idx uint64) (err error) {
+	select {
+	case b.slots <- struct{}{}:
+		return
+	default:
+		b.o.dropped(idx)
+		return ErrFull
+	}
+}
+
+func (b *bulkhead) run(ctx context.Context, f func() error) (err error) {
+	idx := b.o.next()
+	if err = b.acquire(ctx, idx, true); err != nil {
+		return
+	}
+	defer func() { <-b.slots }()
+	return b.o.observe(idx, f)
+}
+
+func (b *bulkhead) TryRun() (err error) {
+	idx := b.o.next()
+	if err = b.tryAcquire(idx); err != nil {
+		return
+	}
+	defer func() { <-b.slots }()
+	return b.o.observe(idx, b.f)
+}
+
+func (b *bulkhead) Run() (err error) {
+	return b.run(context.Background(), b.f)
+}
+
+func (b *bulkhead) RunContext(ctx context.Context) (err error) {
+	return b.run(ctx, b.f)
+}
+
+func (b *bulkhead) Wrap(f func() error) func() error {
+	return func() error {
+		return b.run(context.Background(), f)
+	}
+}
+
+func (b *bulkhead) Lock() (release func()) {
+	b.acquire(context.Background(), b.o.next(), false)
+	return b.locked()
+}
+
+func (b *bulkhead) TryLock() (release func(), err error) {
+	if err = b.tryAcquire(b.o.next()); err != nil {
+		return
+	}
+	return b.locked(), nil
+}
+
+func (b *bulkhead) LockContext(ctx context.Context) (release func(), err error) {
+	if err = b.acquire(ctx, b.o.next(), true); err != nil {
+		return
+	}
+	return b.locked(), nil
+}
+
+// locked creates release function of a slot
+func (b *bulkhead) locked() (release func()) {
+	once := &sync.Once{}
+	return func() {
+		once.Do(func() {
+			<-b.slots
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+// waitFor polls cond, as some states of Bulkhead cannot be observed by clock
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("timeout")
+}
+
+func TestBulkhead(t *testing.T) {
+	release := make(chan struct{})
+	b := NewBulkhead(2, func() error {
+		<-release
+		return nil
+	}, WithQueue(1))
+
+	done := make(chan error)
+	for i := 0; i < 3; i++ {
+		go func() { done <- b.Run() }()
+	}
+	waitFor(t, func() bool { return b.InFlight() == 2 && b.Queued() == 1 })
+
+	if err := b.TryRun(); err != ErrFull {
+		t.Fatal("unexpected TryRun error: ", err)
+	}
+	if err := b.Run(); err != ErrFull {
+		t.Fatal("unexpected Run error: ", err)
+	}
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		if err := <-done; err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+	}
+	if b.InFlight() != 0 || b.Queued() != 0 {
+		t.Fatalf("unexpected state: %d, %d", b.InFlight(), b.Queued())
+	}
+}
+
+func TestBulkheadQueueTimeout(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	ob := NewMemoryObserver()
+	b := NewBulkhead(1, func() error { return nil },
+		WithClock(c), WithQueueTimeout(time.Second), WithObserver(ob))
+
+	release := b.Lock()
+
+	done := make(chan error)
+	go func() { done <- b.Run() }()
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	if err := <-done; err != ErrQueueTimeout {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { done <- b.RunContext(ctx) }()
+	c.BlockUntil(1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	go func() { done <- b.Run() }()
+	c.BlockUntil(2) // timer of cancelled one is still there
+	c.Advance(500 * time.Millisecond)
+	release()
+	release() // no-op
+	if err := <-done; err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	s := ob.Stats("")
+	if s.Finished != 1 || s.Dropped != 2 || s.Waited != 1 || s.WaitTotal != 500*time.Millisecond {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	if b.InFlight() != 0 {
+		t.Fatal("unexpected in-flight: ", b.InFlight())
+	}
+}
+
+func TestBulkheadNoQueue(t *testing.T) {
+	b := NewBulkhead(0, func() error { return nil }, WithQueue(0))
+	release, err := b.LockContext(context.Background())
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	defer release()
+
+	if _, err := b.TryLock(); err != ErrFull {
+		t.Fatal("unexpected error: ", err)
+	}
+	if _, err := b.LockContext(context.Background()); err != ErrFull {
+		t.Fatal("unexpected error: ", err)
+	}
+}
+
+func TestBulkheadLock(t *testing.T) {
+	b := NewBulkhead(1, func() error { return nil }, WithQueue(0))
+	release := b.Lock()
+
+	// Lock() ignores limits of the queue
+	locked := make(chan func())
+	go func() { locked <- b.Lock() }()
+	waitFor(t, func() bool { return b.Queued() == 1 })
+	release()
+	release = <-locked
+	if b.InFlight() != 1 || b.Queued() != 0 {
+		t.Fatalf("unexpected state: %d, %d", b.InFlight(), b.Queued())
+	}
+	release()
+
+	// wrapped functions share the slot
+	release = b.Lock()
+	f := Bulkheaded(b)(func() error { return nil })
+	if err := f(); err != ErrFull {
+		t.Fatal("unexpected error: ", err)
+	}
+	release()
+	if err := f(); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+}
@@ -0,0 +1,272 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("CircuitBreaker: circuit is open")
+
+// CircuitState is the state of a CircuitBreaker
+type CircuitState int
+
+const (
+	// calls are passed through, and failures are counted
+	CircuitClosed CircuitState = iota
+	// calls are rejected with ErrCircuitOpen
+	CircuitOpen
+	// limited number of probe calls are passed through to test the dependency
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerConfig configures a CircuitBreaker
+//
+// Zero value of each field means default value, which is documented below. If
+// neither ConsecutiveFailures nor FailureRatio is set, ConsecutiveFailures is 5.
+type CircuitBreakerConfig struct {
+	// Opens the circuit after this many consecutive failures.
+	ConsecutiveFailures uint64
+	// Opens the circuit when ratio of failed calls within Window reaches it.
+	FailureRatio float64
+	// Size of rolling window used by FailureRatio, default to 10s.
+	Window time.Duration
+	// FailureRatio is ignored if there are fewer calls within Window, default
+	// to 1.
+	MinRequests uint64
+	// How long the circuit stays open before half-open, default to 10s.
+	Cooldown time.Duration
+	// Max number of concurrent probe calls in half-open state, default to 1.
+	// The circuit is closed after this many consecutive successful probes.
+	HalfOpenProbes uint64
+	// Called after state is changed, outside of internal lock.
+	OnStateChange func(from, to CircuitState)
+}
+
+const cbBuckets = 10
+
+type cbBucket struct {
+	id       int64
+	total    uint64
+	failures uint64
+}
+
+// CircuitBreaker stops calling a function which keeps failing
+//
+// It starts in closed state, where every call is passed through. When failures
+// reach the threshold, it opens and rejects all calls with ErrCircuitOpen for a
+// cooldown period. After that, it becomes half-open and lets few probe calls
+// through: the circuit is closed if they succeed, or opened again if any of them
+// fails.
+//
+// It composes with other helpers, for example
+//
+//    cb := NewCircuitBreaker(CircuitBreakerConfig{ConsecutiveFailures: 3})
+//    err := TryAtMost(5, RunFailedAtLeast(time.Second, cb.Wrap(callAPI)))
+type CircuitBreaker struct {
+	lock sync.Mutex
+	cfg  CircuitBreakerConfig
+	o    *options
+
+	state        CircuitState
+	generation   uint64
+	openedAt     time.Time
+	consecutive  uint64
+	buckets      [cbBuckets]cbBucket
+	probes       uint64
+	probeSuccess uint64
+}
+
+// NewCircuitBreaker creates a CircuitBreaker in closed state
+//
+// Supported options: WithClock, WithObserver, WithName. Rejected calls are
+// reported as Dropped.
+func NewCircuitBreaker(cfg CircuitBreakerConfig, opts ...Option) (ret *CircuitBreaker) {
+	if cfg.ConsecutiveFailures == 0 && cfg.FailureRatio <= 0 {
+		cfg.ConsecutiveFailures = 5
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.MinRequests == 0 {
+		cfg.MinRequests = 1
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 10 * time.Second
+	}
+	if cfg.HalfOpenProbes == 0 {
+		cfg.HalfOpenProbes = 1
+	}
+
+	return &CircuitBreaker{
+		cfg: cfg,
+		o:   newOptions(opts),
+	}
+}
+
+// State returns current state of the circuit
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	if cb.state == CircuitOpen && cb.cooledDown(cb.o.clock.Now()) {
+		return CircuitHalfOpen
+	}
+	return cb.state
+}
+
+// Run calls f if the circuit allows, or returns ErrCircuitOpen
+func (cb *CircuitBreaker) Run(f func() error) (err error) {
+	idx := cb.o.next()
+	gen, err := cb.before()
+	if err != nil {
+		cb.o.dropped(idx)
+		return
+	}
+
+	err = cb.o.observe(idx, f)
+	cb.after(gen, err == nil)
+	return
+}
+
+// Wrap creates a function which calls f through the circuit breaker
+func (cb *CircuitBreaker) Wrap(f func() error) func() error {
+	return func() error {
+		return cb.Run(f)
+	}
+}
+
+func (cb *CircuitBreaker) cooledDown(now time.Time) bool {
+	return now.Sub(cb.openedAt) >= cb.cfg.Cooldown
+}
+
+func (cb *CircuitBreaker) before() (gen uint64, err error) {
+	cb.lock.Lock()
+	notify := func() {}
+	if cb.state == CircuitOpen && cb.cooledDown(cb.o.clock.Now()) {
+		notify = cb.transit(CircuitHalfOpen)
+	}
+
+	switch cb.state {
+	case CircuitOpen:
+		err = ErrCircuitOpen
+	case CircuitHalfOpen:
+		if cb.probes >= cb.cfg.HalfOpenProbes {
+			err = ErrCircuitOpen
+			break
+		}
+		cb.probes++
+	}
+	gen = cb.generation
+	cb.lock.Unlock()
+
+	notify()
+	return
+}
+
+func (cb *CircuitBreaker) after(gen uint64, ok bool) {
+	cb.lock.Lock()
+	notify := func() {}
+	if gen != cb.generation {
+		// state has been changed during the call
+		cb.lock.Unlock()
+		return
+	}
+
+	switch cb.state {
+	case CircuitClosed:
+		if cb.record(cb.o.clock.Now(), ok) {
+			notify = cb.transit(CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		cb.probes--
+		if !ok {
+			notify = cb.transit(CircuitOpen)
+			break
+		}
+		cb.probeSuccess++
+		if cb.probeSuccess >= cb.cfg.HalfOpenProbes {
+			notify = cb.transit(CircuitClosed)
+		}
+	}
+	cb.lock.Unlock()
+
+	notify()
+}
+
+// record counts result of a call in closed state, and reports if the circuit
+// should be opened
+func (cb *CircuitBreaker) record(now time.Time, ok bool) (trip bool) {
+	if ok {
+		cb.consecutive = 0
+	} else {
+		cb.consecutive++
+	}
+	if n := cb.cfg.ConsecutiveFailures; n > 0 && cb.consecutive >= n {
+		return true
+	}
+	if cb.cfg.FailureRatio <= 0 {
+		return false
+	}
+
+	width := int64(cb.cfg.Window / cbBuckets)
+	if width <= 0 {
+		width = 1
+	}
+	id := now.UnixNano() / width
+	b := &cb.buckets[id%cbBuckets]
+	if b.id != id {
+		*b = cbBucket{id: id}
+	}
+	b.total++
+	if !ok {
+		b.failures++
+	}
+
+	var total, failures uint64
+	for _, b := range cb.buckets {
+		if b.id > id-cbBuckets {
+			total += b.total
+			failures += b.failures
+		}
+	}
+	return total >= cb.cfg.MinRequests &&
+		float64(failures)/float64(total) >= cb.cfg.FailureRatio
+}
+
+// transit changes state and resets counters, must be called with lock held
+//
+// Returned function notifies OnStateChange, call it after releasing the lock.
+func (cb *CircuitBreaker) transit(to CircuitState) (notify func()) {
+	from := cb.state
+	cb.state = to
+	cb.generation++
+	cb.consecutive = 0
+	cb.buckets = [cbBuckets]cbBucket{}
+	cb.probes = 0
+	cb.probeSuccess = 0
+	if to == CircuitOpen {
+		cb.openedAt = cb.o.clock.Now()
+	}
+
+	return func() {
+		if cb.cfg.OnStateChange != nil {
+			cb.cfg.OnStateChange(from, to)
+		}
+	}
+}
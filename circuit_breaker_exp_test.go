@@ -0,0 +1,33 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+func ExampleCircuitBreaker() {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		ConsecutiveFailures: 2,
+		Cooldown:            time.Minute,
+		OnStateChange: func(from, to CircuitState) {
+			fmt.Printf("circuit: %s -> %s\n", from, to)
+		},
+	})
+	callAPI := cb.Wrap(func() error {
+		fmt.Println("call api")
+		return errors.New("service unavailable")
+	})
+
+	// api is not called after circuit is open
+	fmt.Println(TryAtMost(4, callAPI))
+
+	// output: call api
+	// call api
+	// circuit: closed -> open
+	// CircuitBreaker: circuit is open
+}
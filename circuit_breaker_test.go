@@ -0,0 +1,163 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+func TestCircuitBreakerConsecutive(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	theErr := errors.New("the error")
+	changes := []CircuitState{}
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		ConsecutiveFailures: 3,
+		Cooldown:            time.Second,
+		OnStateChange: func(from, to CircuitState) {
+			changes = append(changes, to)
+		},
+	}, WithClock(c))
+
+	var e error
+	cnt := 0
+	f := cb.Wrap(func() error {
+		cnt++
+		return e
+	})
+
+	// success resets counter
+	e = theErr
+	f()
+	f()
+	e = nil
+	f()
+	e = theErr
+	f()
+	f()
+	if s := cb.State(); s != CircuitClosed {
+		t.Fatalf("expected closed, got %s", s)
+	}
+	f()
+	if s := cb.State(); s != CircuitOpen {
+		t.Fatalf("expected open, got %s", s)
+	}
+
+	if err := f(); err != ErrCircuitOpen {
+		t.Fatal("unexpected error: ", err)
+	}
+	if cnt != 6 {
+		t.Fatalf("expected f to run 6 times, got %d", cnt)
+	}
+
+	// failed probe
+	c.Advance(time.Second)
+	if s := cb.State(); s != CircuitHalfOpen {
+		t.Fatalf("expected half-open, got %s", s)
+	}
+	if err := f(); err != theErr {
+		t.Fatal("unexpected error: ", err)
+	}
+	if s := cb.State(); s != CircuitOpen {
+		t.Fatalf("expected open, got %s", s)
+	}
+
+	// successful probe
+	c.Advance(time.Second)
+	e = nil
+	if err := f(); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if s := cb.State(); s != CircuitClosed {
+		t.Fatalf("expected closed, got %s", s)
+	}
+
+	expect := []CircuitState{
+		CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed,
+	}
+	if len(changes) != len(expect) {
+		t.Fatalf("expected %v, got %v", expect, changes)
+	}
+	for idx, s := range expect {
+		if changes[idx] != s {
+			t.Fatalf("expected %v, got %v", expect, changes)
+		}
+	}
+}
+
+func TestCircuitBreakerRatio(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	theErr := errors.New("the error")
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		FailureRatio: 0.5,
+		Window:       10 * time.Second,
+		MinRequests:  4,
+	}, WithClock(c))
+	ok := func() error { return nil }
+	fail := func() error { return theErr }
+
+	// 1/4 failed
+	cb.Run(fail)
+	cb.Run(ok)
+	cb.Run(ok)
+	cb.Run(ok)
+
+	// old results are dropped, 2/3 failed but fewer than MinRequests
+	c.Advance(10 * time.Second)
+	cb.Run(fail)
+	cb.Run(ok)
+	cb.Run(fail)
+	if s := cb.State(); s != CircuitClosed {
+		t.Fatalf("expected closed, got %s", s)
+	}
+
+	// 2/4 failed
+	cb.Run(ok)
+	if s := cb.State(); s != CircuitOpen {
+		t.Fatalf("expected open, got %s", s)
+	}
+}
+
+func TestCircuitBreakerProbes(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	theErr := errors.New("the error")
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		ConsecutiveFailures: 1,
+		Cooldown:            time.Second,
+		HalfOpenProbes:      2,
+	}, WithClock(c))
+
+	cb.Run(func() error { return theErr })
+	c.Advance(time.Second)
+
+	// two probes are running, third one is rejected
+	started := make(chan struct{})
+	release := make(chan struct{})
+	results := make(chan error)
+	probe := func() {
+		results <- cb.Run(func() error {
+			started <- struct{}{}
+			<-release
+			return nil
+		})
+	}
+	go probe()
+	go probe()
+	<-started
+	<-started
+	if err := cb.Run(func() error { return nil }); err != ErrCircuitOpen {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	close(release)
+	<-results
+	<-results
+	if s := cb.State(); s != CircuitClosed {
+		t.Fatalf("expected closed, got %s", s)
+	}
+}
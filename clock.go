@@ -0,0 +1,41 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import "time"
+
+// Clock is the source of time used by time-based helpers
+//
+// Helpers use RealClock() unless another Clock is given by WithClock(). A fake
+// implementation which is advanced manually can be found in subpackage
+// routinestest, which makes it possible to test your code without real sleeps.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// RealClock returns a Clock which delegates to package time
+func RealClock() Clock {
+	return realClock{}
+}
+
+// newTimer is like c.After, but returns stop to release the timer early
+//
+// It matters only to RealClock(), as timers created by time.After are not
+// released until fired in older Go.
+func newTimer(c Clock, d time.Duration) (ch <-chan time.Time, stop func()) {
+	if _, ok := c.(realClock); ok {
+		t := time.NewTimer(d)
+		return t.C, func() { t.Stop() }
+	}
+	return c.After(d), func() {}
+}
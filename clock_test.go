@@ -0,0 +1,24 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+var _ Clock = (*routinestest.Clock)(nil)
+
+func TestRealClock(t *testing.T) {
+	c := RealClock()
+	begin := c.Now()
+	c.Sleep(time.Millisecond)
+	<-c.After(time.Millisecond)
+	if d := time.Since(begin); d < 2*time.Millisecond {
+		t.Fatalf("expected at least 2ms, got %v", d)
+	}
+}
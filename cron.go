@@ -0,0 +1,343 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCron is wrapped in errors returned by ParseCron
+	ErrInvalidCron = errors.New("Cron: invalid spec")
+	// ErrNoSchedule is sent to Err if the schedule never fires again
+	ErrNoSchedule = errors.New("Cron: no next activation time")
+)
+
+// Schedule computes activation times of a periodic task
+type Schedule interface {
+	// Next returns first activation time after t, or zero time if none
+	Next(t time.Time) time.Time
+}
+
+// every is the Schedule of "@every"
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// cronSchedule is the Schedule of cron spec, fields are bit sets
+type cronSchedule struct {
+	sec, min, hour, dom, month, dow uint64
+
+	// field is "*" or "?", see matchDay and Next
+	hourStar, domStar, dowStar bool
+}
+
+type cronField struct {
+	min, max uint
+	names    []string
+}
+
+var (
+	cronSec   = cronField{min: 0, max: 59}
+	cronMin   = cronField{min: 0, max: 59}
+	cronHour  = cronField{min: 0, max: 23}
+	cronDom   = cronField{min: 1, max: 31}
+	cronMonth = cronField{min: 1, max: 12, names: []string{
+		"", "jan", "feb", "mar", "apr", "may", "jun",
+		"jul", "aug", "sep", "oct", "nov", "dec",
+	}}
+	// 7 is also sunday
+	cronDow = cronField{min: 0, max: 7, names: []string{
+		"sun", "mon", "tue", "wed", "thu", "fri", "sat",
+	}}
+)
+
+var cronShortcuts = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// ParseCron parses a cron spec into Schedule
+//
+// Supported formats are
+//
+//   - 5 fields: minute, hour, day of month, month and day of week
+//   - 6 fields: second, followed by 5 fields above
+//   - @yearly (or @annually), @monthly, @weekly, @daily (or @midnight), @hourly
+//   - @every <duration>, duration is parsed by time.ParseDuration
+//
+// A field can be "*", "?", a value, a range "1-5", a step "*/10", "1-30/2" or
+// "5/15", or a comma separated list of them. Month and day of week accept names
+// like "jan" and "mon", case-insensitive. Sunday is either 0 or 7.
+//
+// Like standard cron, if both day of month and day of week are restricted, a day
+// matches if either of them matches.
+func ParseCron(spec string) (ret Schedule, err error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		d, e := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if e != nil || d <= 0 {
+			return nil, fmt.Errorf("%w %q: invalid duration", ErrInvalidCron, spec)
+		}
+		return every(d), nil
+	}
+	fields := strings.Fields(spec)
+	if strings.HasPrefix(spec, "@") {
+		s, ok := cronShortcuts[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("%w %q: unknown shortcut", ErrInvalidCron, spec)
+		}
+		fields = strings.Fields(s)
+	}
+
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("%w %q: expected 5 or 6 fields, got %d", ErrInvalidCron, spec, len(fields))
+	}
+
+	s := &cronSchedule{}
+	parsers := []struct {
+		f    cronField
+		bits *uint64
+		star *bool
+	}{
+		{f: cronSec, bits: &s.sec},
+		{f: cronMin, bits: &s.min},
+		{f: cronHour, bits: &s.hour, star: &s.hourStar},
+		{f: cronDom, bits: &s.dom, star: &s.domStar},
+		{f: cronMonth, bits: &s.month},
+		{f: cronDow, bits: &s.dow, star: &s.dowStar},
+	}
+	for idx, p := range parsers {
+		bits, star, e := p.f.parse(fields[idx])
+		if e != nil {
+			return nil, fmt.Errorf("%w %q: field %q: %v", ErrInvalidCron, spec, fields[idx], e)
+		}
+		*p.bits = bits
+		if p.star != nil {
+			*p.star = star
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	return s, nil
+}
+
+func (f cronField) value(s string) (ret uint, err error) {
+	for idx, n := range f.names {
+		if n != "" && strings.EqualFold(s, n) {
+			return uint(idx), nil
+		}
+	}
+
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if ret = uint(v); ret < f.min || ret > f.max {
+		return 0, fmt.Errorf("%d is out of range [%d, %d]", ret, f.min, f.max)
+	}
+	return
+}
+
+func (f cronField) parse(s string) (bits uint64, star bool, err error) {
+	for _, part := range strings.Split(s, ",") {
+		r, stepStr, hasStep := strings.Cut(part, "/")
+		step := uint(1)
+		if hasStep {
+			v, e := strconv.ParseUint(stepStr, 10, 8)
+			if e != nil || v == 0 {
+				return 0, false, fmt.Errorf("invalid step %q", stepStr)
+			}
+			step = uint(v)
+		}
+
+		var lo, hi uint
+		switch {
+		case r == "*" || r == "?":
+			lo, hi = f.min, f.max
+			star = star || step == 1
+		default:
+			loStr, hiStr, isRange := strings.Cut(r, "-")
+			if lo, err = f.value(loStr); err != nil {
+				return
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(hiStr); err != nil {
+					return
+				}
+			} else if hasStep {
+				hi = f.max
+			}
+			if lo > hi {
+				return 0, false, fmt.Errorf("invalid range %q", r)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return
+}
+
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if !s.domStar && !s.dowStar {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// Next implements Schedule
+//
+// Computation is done in location of t. When clocks go forward (DST), wall
+// times which do not exist are skipped. When clocks go back, repeated wall times
+// fire once, unless hour field is "*".
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	prev := t
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	// longest gap between activations is 8 years: Feb 29 across 2100, 2200 ...
+	limit := t.Year() + 8
+
+	for t.Year() <= limit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		// sub-day fields are moved by absolute time, as wall clock is ambiguous
+		// around DST transitions
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Add(time.Hour -
+				time.Duration(t.Minute())*time.Minute -
+				time.Duration(t.Second())*time.Second)
+			continue
+		}
+		if s.min&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute - time.Duration(t.Second())*time.Second)
+			continue
+		}
+		if s.sec&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+		if !s.hourStar && !wallAfter(t, prev) {
+			// repeated wall time when clocks go back
+			t = t.Add(time.Second)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// wallAfter reports whether wall clock of a is after b
+func wallAfter(a, b time.Time) bool {
+	b = b.In(a.Location())
+	return time.Date(a.Year(), a.Month(), a.Day(), a.Hour(), a.Minute(), a.Second(), 0, time.UTC).
+		After(time.Date(b.Year(), b.Month(), b.Day(), b.Hour(), b.Minute(), b.Second(), 0, time.UTC))
+}
+
+// WithLocation sets time zone used to compute schedule, default to time.Local
+func WithLocation(loc *time.Location) Option {
+	return func(o *options) {
+		o.loc = loc
+	}
+}
+
+// Cron parses spec with ParseCron, and runs task at every activation time
+//
+// See CronSchedule for detail.
+//
+// Supported options: WithClock, WithLocation, WithObserver, WithName
+func Cron(spec string, task func() error, opts ...Option) (ret InfiniteLoopControl, err error) {
+	s, err := ParseCron(spec)
+	if err != nil {
+		return
+	}
+	return CronSchedule(s, task, opts...), nil
+}
+
+// CronSchedule runs task at every activation time of s
+//
+// Like InfiniteLoop, loop stops when task returns an error, which is sent to Err,
+// and it will not interrupt running task. Activation times missed due to long
+// running task are skipped. ErrNoSchedule is sent to Err if s never fires again.
+//
+//    // every weekday at 02:30 in Taipei
+//    ctrl, err := Cron("30 2 * * mon-fri", task, WithLocation(taipei))
+//
+// Supported options: WithClock, WithLocation, WithObserver, WithName
+func CronSchedule(s Schedule, task func() error, opts ...Option) (ret InfiniteLoopControl) {
+	o := newOptions(opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	err := make(chan error)
+	go func() {
+		err <- o.runSchedule(ctx, s, task)
+		close(err)
+	}()
+
+	return InfiniteLoopControl{
+		Cancel: cancel,
+		Err:    err,
+	}
+}
+
+func (o *options) runSchedule(ctx context.Context, s Schedule, task func() error) error {
+	last := o.clock.Now().In(o.loc)
+	for {
+		now := o.clock.Now().In(o.loc)
+		next := s.Next(last)
+		if !next.IsZero() && next.Before(now) {
+			next = s.Next(now)
+		}
+		if next.IsZero() {
+			return ErrNoSchedule
+		}
+
+		timer, stop := newTimer(o.clock, next.Sub(now))
+		select {
+		case <-ctx.Done():
+			stop()
+			return ctx.Err()
+		case <-timer:
+		}
+		// stopped while waiting
+		if e := ctx.Err(); e != nil {
+			return e
+		}
+
+		last = next
+		if e := o.observe(o.next(), task); e != nil {
+			return e
+		}
+	}
+}
@@ -0,0 +1,27 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"fmt"
+	"time"
+)
+
+func ExampleParseCron() {
+	s, err := ParseCron("30 2 * * mon-fri")
+	if err != nil {
+		panic(err)
+	}
+
+	t := time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC) // friday
+	for i := 0; i < 3; i++ {
+		t = s.Next(t)
+		fmt.Println(t.Format("Mon 15:04"))
+	}
+
+	// output: Mon 02:30
+	// Tue 02:30
+	// Wed 02:30
+}
@@ -0,0 +1,272 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+func TestParseCronError(t *testing.T) {
+	cases := []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 * ",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"@every",
+		"@every -1s",
+		"@weekdays",
+	}
+
+	for _, c := range cases {
+		if _, err := ParseCron(c); !errors.Is(err, ErrInvalidCron) {
+			t.Errorf("%q: unexpected error: %v", c, err)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) // monday
+	cases := []struct {
+		spec   string
+		from   time.Time
+		expect []time.Time
+	}{
+		{
+			spec: "*/15 * * * *",
+			from: base,
+			expect: []time.Time{
+				base.Add(15 * time.Minute),
+				base.Add(30 * time.Minute),
+			},
+		},
+		{
+			spec: "30 2 * * mon-fri",
+			from: time.Date(2024, 1, 5, 3, 0, 0, 0, time.UTC), // friday
+			expect: []time.Time{
+				time.Date(2024, 1, 8, 2, 30, 0, 0, time.UTC),
+				time.Date(2024, 1, 9, 2, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec: "10,20 * * * * *",
+			from: base,
+			expect: []time.Time{
+				base.Add(10 * time.Second),
+				base.Add(20 * time.Second),
+				base.Add(time.Minute + 10*time.Second),
+			},
+		},
+		{
+			// either day of month or day of week
+			spec: "0 0 13 * 5",
+			from: base,
+			expect: []time.Time{
+				time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 13, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec:   "0 0 * * 7",
+			from:   base,
+			expect: []time.Time{time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			spec: "0 0 29 FEB *",
+			from: base,
+			expect: []time.Time{
+				time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+				time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			// no leap day in 2100
+			spec: "0 0 29 2 *",
+			from: time.Date(2097, 3, 1, 0, 0, 0, 0, time.UTC),
+			expect: []time.Time{
+				time.Date(2104, 2, 29, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec:   "0 0 30 2 *",
+			from:   base,
+			expect: []time.Time{{}},
+		},
+		{
+			spec:   "@monthly",
+			from:   base,
+			expect: []time.Time{time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			spec: "@every 90m",
+			from: base.Add(time.Millisecond),
+			expect: []time.Time{
+				base.Add(90*time.Minute + time.Millisecond),
+				base.Add(180*time.Minute + time.Millisecond),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.spec, func(t *testing.T) {
+			s, err := ParseCron(c.spec)
+			if err != nil {
+				t.Fatal("unexpected error: ", err)
+			}
+			cur := c.from
+			for idx, e := range c.expect {
+				cur = s.Next(cur)
+				if !cur.Equal(e) {
+					t.Fatalf("#%d: expected %v, got %v", idx, e, cur)
+				}
+			}
+		})
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal("cannot load location: ", err)
+	}
+	cases := []struct {
+		name   string
+		spec   string
+		from   time.Time
+		expect []time.Time
+	}{
+		{
+			// 2024-03-10 02:00 EST jumps to 03:00 EDT
+			name: "skip nonexistent",
+			spec: "30 2 * * *",
+			from: time.Date(2024, 3, 9, 12, 0, 0, 0, loc),
+			expect: []time.Time{
+				time.Date(2024, 3, 9, 2, 30, 0, 0, loc).AddDate(0, 0, 2),
+			},
+		},
+		{
+			name: "hourly across spring forward",
+			spec: "0 * * * *",
+			from: time.Date(2024, 3, 10, 0, 30, 0, 0, loc),
+			expect: []time.Time{
+				time.Date(2024, 3, 10, 1, 0, 0, 0, loc),
+				time.Date(2024, 3, 10, 3, 0, 0, 0, loc),
+			},
+		},
+		{
+			// 2024-11-03 02:00 EDT goes back to 01:00 EST
+			name: "repeated once",
+			spec: "30 1 * * *",
+			from: time.Date(2024, 11, 3, 0, 0, 0, 0, loc),
+			expect: []time.Time{
+				time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), // 01:30 EDT
+				time.Date(2024, 11, 4, 6, 30, 0, 0, time.UTC), // 01:30 EST next day
+			},
+		},
+		{
+			name: "hourly across fall back",
+			spec: "0 * * * *",
+			from: time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), // 01:30 EDT
+			expect: []time.Time{
+				time.Date(2024, 11, 3, 6, 0, 0, 0, time.UTC), // 01:00 EST
+				time.Date(2024, 11, 3, 7, 0, 0, 0, time.UTC), // 02:00 EST
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := ParseCron(c.spec)
+			if err != nil {
+				t.Fatal("unexpected error: ", err)
+			}
+			cur := c.from.In(loc)
+			for idx, e := range c.expect {
+				cur = s.Next(cur)
+				if !cur.Equal(e) {
+					t.Fatalf("#%d: expected %v, got %v", idx, e.In(loc), cur)
+				}
+			}
+		})
+	}
+}
+
+func TestCron(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	c := routinestest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, loc))
+	cnt := 0
+	ctrl, err := Cron("0 * * * *", func() error {
+		cnt++
+		c.Advance(2 * time.Hour) // misses next activation
+		return nil
+	}, WithClock(c), WithLocation(loc))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	c.BlockUntil(1)
+	c.Advance(time.Hour)
+	c.BlockUntil(1)
+	if cnt != 1 {
+		t.Fatal("expected run once, got ", cnt)
+	}
+	if now := c.Now(); now.Hour() != 3 {
+		t.Fatal("unexpected time: ", now)
+	}
+
+	c.Advance(time.Hour)
+	c.BlockUntil(1)
+	if cnt != 2 {
+		t.Fatal("expected run twice, got ", cnt)
+	}
+
+	ctrl.Cancel()
+	if err := <-ctrl.Err; err != context.Canceled {
+		t.Fatal("unexpected error: ", err)
+	}
+	if _, ok := <-ctrl.Err; ok {
+		t.Fatal("expected Err to be closed")
+	}
+}
+
+func TestCronStop(t *testing.T) {
+	theErr := errors.New("")
+	c := routinestest.NewClock(time.Now())
+	ctrl := CronSchedule(every(time.Second), func() error {
+		return theErr
+	}, WithClock(c))
+
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	if err := <-ctrl.Err; err != theErr {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	ctrl, err := Cron("0 0 30 2 *", func() error { return nil }, WithClock(c))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := <-ctrl.Err; err != ErrNoSchedule {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	if _, err := Cron("* * *", nil); err == nil {
+		t.Fatal("expected error")
+	}
+}
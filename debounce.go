@@ -0,0 +1,176 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"sync"
+	"time"
+)
+
+// sharedCall is an execution of f shared by several callers
+type sharedCall struct {
+	done chan struct{}
+	err  error
+}
+
+func newSharedCall() *sharedCall {
+	return &sharedCall{done: make(chan struct{})}
+}
+
+func (c *sharedCall) wait() error {
+	<-c.done
+	return c.err
+}
+
+func (c *sharedCall) finish(err error) {
+	c.err = err
+	close(c.done)
+}
+
+// Debounce runs f once after calls have been quiet for dur
+//
+// It guarantees:
+//      - calls in a burst are merged into one execution, which starts dur after
+//        last call of the burst
+//      - every call blocks until the execution is done, and gets its result
+//      - only one execution is running at the same time
+//
+// Say you have a f() reloads config file
+//
+//    x := Debounce(time.Second, f)
+//    go x() // at 0s
+//    go x() // at 0.5s
+//    go x() // at 1s
+//
+// Config is reloaded only once at 2s, and all three calls get the result.
+//
+// Call it in another goroutine if you don't care about the result.
+//
+// Supported options: WithClock, WithObserver, WithName
+func Debounce(dur time.Duration, f func() error, opts ...Option) func() error {
+	return DebounceMax(dur, 0, f, opts...)
+}
+
+// DebounceMax is identical to Debounce, but a burst is no longer than maxWait
+//
+// Execution starts at most maxWait after first call of the burst, even if calls
+// keep coming. maxWait <= 0 means no limit.
+//
+// Supported options: WithClock, WithObserver, WithName
+func DebounceMax(dur, maxWait time.Duration, f func() error, opts ...Option) func() error {
+	o := newOptions(opts)
+	var (
+		lock     sync.Mutex
+		run      sync.Mutex
+		cur      *sharedCall
+		first    time.Time
+		deadline time.Time
+	)
+
+	return func() error {
+		idx := o.next()
+		lock.Lock()
+		now := o.clock.Now()
+		c := cur
+		leader := c == nil
+		if leader {
+			c = newSharedCall()
+			cur = c
+			first = now
+		}
+		deadline = now.Add(dur)
+		if maxWait > 0 && deadline.After(first.Add(maxWait)) {
+			deadline = first.Add(maxWait)
+		}
+		lock.Unlock()
+
+		if !leader {
+			o.dropped(idx)
+			return c.wait()
+		}
+
+		// first call of the burst waits until calls are quiet, then runs f
+		for {
+			lock.Lock()
+			d := deadline.Sub(o.clock.Now())
+			if d <= 0 {
+				cur = nil
+				lock.Unlock()
+				break
+			}
+			lock.Unlock()
+			o.clock.Sleep(d)
+		}
+		o.waited(idx, o.clock.Now().Sub(now))
+
+		run.Lock()
+		defer run.Unlock()
+		c.finish(o.observe(idx, f))
+		return c.err
+	}
+}
+
+// OnceWithinTrailing is identical to OnceWithin, but guarantees a trailing call
+//
+// Calls within duration are not ignored, they're merged into one execution which
+// starts when the duration is reached. They block until the execution is done, and
+// get its result.
+//
+// Say you have a f() prints "test", and costs 0.1s each call
+//
+//    x := OnceWithinTrailing(time.Second, f)
+//    go x() // at 0s, prints "test" immediately
+//    go x() // at 0.2s, merged with next call, prints "test" at 1s
+//    go x() // at 0.5s, merged with previous call
+//    time.Sleep(3*time.Second)
+//    go x() // at 3s, prints "test" immediately
+//
+// Supported options: WithClock, WithObserver, WithName
+func OnceWithinTrailing(dur time.Duration, f func() error, opts ...Option) func() error {
+	o := newOptions(opts)
+	var (
+		lock sync.Mutex
+		run  sync.Mutex
+		cur  *sharedCall
+		last time.Time // zero time, so first call is never blocked
+	)
+
+	return func() error {
+		idx := o.next()
+		lock.Lock()
+		if c := cur; c != nil {
+			lock.Unlock()
+			o.dropped(idx)
+			return c.wait()
+		}
+
+		now := o.clock.Now()
+		if d := now.Sub(last); d > dur {
+			last = now
+			lock.Unlock()
+
+			run.Lock()
+			defer run.Unlock()
+			return o.observe(idx, f)
+		}
+
+		// first dropped call waits for the window, and runs f for all of them
+		c := newSharedCall()
+		cur = c
+		at := last.Add(dur)
+		lock.Unlock()
+
+		o.sleep(idx, at.Sub(now))
+		lock.Lock()
+		cur = nil
+		last = o.clock.Now()
+		lock.Unlock()
+
+		run.Lock()
+		defer run.Unlock()
+		c.finish(o.observe(idx, f))
+		return c.err
+	}
+}
@@ -0,0 +1,36 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+func ExampleDebounce() {
+	cnt := 0
+	reload := Debounce(10*time.Millisecond, func() error {
+		cnt++
+		fmt.Println("reload config")
+		return nil
+	})
+
+	// config file is changed several times in a short time
+	wg := &sync.WaitGroup{}
+	for x := 0; x < 3; x++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reload()
+		}()
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+	fmt.Println(cnt)
+
+	// output: reload config
+	// 1
+}
@@ -0,0 +1,131 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+// dropSignal is an Observer notifying ch when a call is merged into another one
+type dropSignal struct {
+	nopObserver
+	ch chan struct{}
+}
+
+func (s dropSignal) Dropped(Event) { s.ch <- struct{}{} }
+
+func newDropSignal() dropSignal {
+	return dropSignal{ch: make(chan struct{}, 1)}
+}
+
+func TestDebounce(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	cnt := 0
+	merged := newDropSignal()
+	f := Debounce(time.Second, func() error {
+		cnt++
+		return errFailed("result")
+	}, WithClock(c), WithObserver(merged))
+
+	results := make(chan error)
+	call := func() { results <- f() }
+
+	go call()
+	c.BlockUntil(1)
+	c.Advance(500 * time.Millisecond)
+	go call()
+	<-merged.ch
+
+	// first sleep ends, but burst is extended by second call
+	c.Advance(500 * time.Millisecond)
+	c.BlockUntil(1)
+	if cnt != 0 {
+		t.Fatalf("expected not run yet, got %d", cnt)
+	}
+
+	c.Advance(500 * time.Millisecond)
+	for x := 0; x < 2; x++ {
+		if err := <-results; err != errFailed("result") {
+			t.Fatal("unexpected error: ", err)
+		}
+	}
+	if cnt != 1 {
+		t.Fatalf("expected run once, got %d", cnt)
+	}
+}
+
+func TestDebounceMax(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	begin := c.Now()
+	var at time.Time
+	merged := newDropSignal()
+	f := DebounceMax(time.Second, 1500*time.Millisecond, func() error {
+		at = c.Now()
+		return nil
+	}, WithClock(c), WithObserver(merged))
+
+	results := make(chan error)
+	call := func() { results <- f() }
+
+	go call()
+	c.BlockUntil(1)
+	c.Advance(900 * time.Millisecond)
+	go call()
+	<-merged.ch
+
+	c.Advance(100 * time.Millisecond)
+	c.BlockUntil(1)
+	c.Advance(500 * time.Millisecond)
+	<-results
+	<-results
+
+	if expect := begin.Add(1500 * time.Millisecond); !at.Equal(expect) {
+		t.Fatalf("expected to run at %v, got %v", expect, at)
+	}
+}
+
+func TestOnceWithinTrailing(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	cnt := 0
+	merged := newDropSignal()
+	f := OnceWithinTrailing(time.Second, func() error {
+		cnt++
+		return nil
+	}, WithClock(c), WithObserver(merged))
+
+	// leading call
+	f()
+	if cnt != 1 {
+		t.Fatalf("expected run once, got %d", cnt)
+	}
+
+	// calls within duration are merged
+	results := make(chan error)
+	call := func() { results <- f() }
+	go call()
+	c.BlockUntil(1)
+	go call()
+	<-merged.ch
+	if cnt != 1 {
+		t.Fatalf("expected run once, got %d", cnt)
+	}
+
+	c.Advance(time.Second)
+	<-results
+	<-results
+	if cnt != 2 {
+		t.Fatalf("expected run twice, got %d", cnt)
+	}
+
+	// trailing call also starts a new window
+	c.Advance(time.Second + 1)
+	f()
+	if cnt != 3 {
+		t.Fatalf("expected run 3 times, got %d", cnt)
+	}
+}
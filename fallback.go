@@ -0,0 +1,70 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+// Fallback creates a function which tries primary and fallbacks in order until
+// first success
+//
+// It is the opposite of TilErr, which stops on first error. Next function is
+// tried only when previous one failed with an error which is not a
+// PermanentError. If no function succeeds, returned error is a MultiError which
+// records every attempt, indexed by order of arguments (primary is #0).
+//
+//    load := Fallback(loadFromServer, loadFromCache, loadDefault)
+func Fallback(primary func() error, fallbacks ...func() error) func() error {
+	return FallbackIf(nil, primary, fallbacks...)
+}
+
+// FallbackIf is identical to Fallback, but errors marked as permanent by c also
+// stop the chain
+//
+//    // server says the resource is gone, cached one is useless
+//    load := FallbackIf(PermanentOn(ErrNotFound), loadFromServer, loadFromCache)
+func FallbackIf(c Classifier, primary func() error, fallbacks ...func() error) func() error {
+	funcs := make([]func() (struct{}, error), 0, len(fallbacks)+1)
+	for _, f := range append([]func() error{primary}, fallbacks...) {
+		f := f
+		funcs = append(funcs, func() (struct{}, error) {
+			return struct{}{}, f()
+		})
+	}
+
+	g := fallbackValue(c, funcs)
+	return func() error {
+		_, err := g()
+		return err
+	}
+}
+
+// FallbackValue is identical to Fallback, but functions return a value
+//
+// Value of the successful one is returned.
+func FallbackValue[T any](primary func() (T, error), fallbacks ...func() (T, error)) func() (T, error) {
+	return FallbackValueIf(nil, primary, fallbacks...)
+}
+
+// FallbackValueIf is identical to FallbackIf, but functions return a value
+func FallbackValueIf[T any](c Classifier, primary func() (T, error), fallbacks ...func() (T, error)) func() (T, error) {
+	return fallbackValue(c, append([]func() (T, error){primary}, fallbacks...))
+}
+
+func fallbackValue[T any](c Classifier, funcs []func() (T, error)) func() (T, error) {
+	return func() (ret T, err error) {
+		var errs MultiError
+		for idx, f := range funcs {
+			v, e := f()
+			if e == nil {
+				return v, nil
+			}
+
+			errs = append(errs, &IndexedError{Index: idx, Err: e})
+			if IsPermanent(e) || (c != nil && c(e)) {
+				break
+			}
+		}
+
+		return ret, errs
+	}
+}
@@ -0,0 +1,22 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"errors"
+	"fmt"
+)
+
+func ExampleFallbackValue() {
+	load := FallbackValue(
+		func() (string, error) { return "", errors.New("server is down") },
+		func() (string, error) { return "", errors.New("cache miss") },
+		func() (string, error) { return "default config", nil },
+	)
+
+	fmt.Println(load())
+
+	// output: default config <nil>
+}
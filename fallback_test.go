@@ -0,0 +1,69 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFallback(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	var trace []int
+	mk := func(idx int, err error) func() error {
+		return func() error {
+			trace = append(trace, idx)
+			return err
+		}
+	}
+
+	if err := Fallback(mk(0, errA), mk(1, nil), mk(2, nil))(); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if len(trace) != 2 {
+		t.Fatalf("unexpected trace: %v", trace)
+	}
+
+	trace = nil
+	err := Fallback(mk(0, errA), mk(1, errB))()
+	var me MultiError
+	if !errors.As(err, &me) || len(me) != 2 {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if me[0].Index != 0 || me[0].Err != errA || me[1].Index != 1 || me[1].Err != errB {
+		t.Fatalf("unexpected error: %v", me)
+	}
+
+	trace = nil
+	err = Fallback(mk(0, Permanent(errA)), mk(1, nil))()
+	if len(trace) != 1 || !errors.Is(err, errA) {
+		t.Fatalf("unexpected result: %v, %v", trace, err)
+	}
+
+	trace = nil
+	err = FallbackIf(PermanentOn(errB), mk(0, errA), mk(1, errB), mk(2, nil))()
+	if len(trace) != 2 || !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("unexpected result: %v, %v", trace, err)
+	}
+}
+
+func TestFallbackValue(t *testing.T) {
+	theErr := errors.New("")
+	v, err := FallbackValue(
+		func() (int, error) { return 0, theErr },
+		func() (int, error) { return 2, nil },
+	)()
+	if err != nil || v != 2 {
+		t.Fatalf("unexpected result: %d, %v", v, err)
+	}
+
+	v, err = FallbackValueIf(PermanentOn(theErr),
+		func() (int, error) { return 1, theErr },
+		func() (int, error) { return 2, nil },
+	)()
+	if err == nil || v != 0 {
+		t.Fatalf("unexpected result: %d, %v", v, err)
+	}
+}
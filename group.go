@@ -0,0 +1,88 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"sync"
+)
+
+// Group runs functions in background with limited concurrency
+//
+// It is much like TilErrAsync, but:
+//
+//    - functions can be added dynamically by Go()
+//    - at most limit functions are running at the same time
+//    - a context is passed to functions, which is cancelled on first error if
+//      failFast is set
+//
+// It still guarantees all functions passed to Go() are executed, and Wait() blocks
+// until all of them are returned. Functions started after cancellation are called
+// with a cancelled context, so they can return immediately.
+type Group struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	failFast bool
+	sem      chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+	err      error
+}
+
+// NewGroup creates a Group, limit <= 0 means no limit
+func NewGroup(ctx context.Context, limit int, failFast bool) (ret *Group) {
+	ret = &Group{failFast: failFast}
+	ret.ctx, ret.cancel = context.WithCancel(ctx)
+	if limit > 0 {
+		ret.sem = make(chan struct{}, limit)
+	}
+
+	return
+}
+
+// Go runs f in background, blocks until there's a free slot
+func (g *Group) Go(f func(ctx context.Context) error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.wg.Add(1)
+
+	go func() {
+		defer g.wg.Done()
+		if g.sem != nil {
+			defer func() { <-g.sem }()
+		}
+
+		if err := f(g.ctx); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				if g.failFast {
+					g.cancel()
+				}
+			})
+		}
+	}()
+}
+
+// Wait blocks until all functions are returned, and returns first error
+//
+// The context passed to functions is cancelled when Wait returns.
+func (g *Group) Wait() (err error) {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
+
+// TilErrAsyncLimit is identical to TilErrAsync, but runs funcs with a Group
+//
+// At most limit funcs are running at the same time, see Group for detail.
+func TilErrAsyncLimit(ctx context.Context, limit int, failFast bool, funcs ...func(ctx context.Context) error) (err error) {
+	g := NewGroup(ctx, limit, failFast)
+	for _, f := range funcs {
+		g.Go(f)
+	}
+
+	return g.Wait()
+}
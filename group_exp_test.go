@@ -0,0 +1,35 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+func ExampleGroup() {
+	urls := make([]string, 1000)
+	var cnt int32
+	crawl := func(url string) func(context.Context) error {
+		return func(ctx context.Context) error {
+			// crawls the page with ctx
+			atomic.AddInt32(&cnt, 1)
+			return nil
+		}
+	}
+
+	// crawls 10 pages at the same time, stops on first error
+	g := NewGroup(context.Background(), 10, true)
+	for _, u := range urls {
+		g.Go(crawl(u))
+	}
+	if err := g.Wait(); err != nil {
+		fmt.Println("an error occurred:", err)
+	}
+	fmt.Println(cnt)
+
+	// output: 1000
+}
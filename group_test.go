@@ -0,0 +1,106 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupLimit(t *testing.T) {
+	var running, max, cnt int32
+	f := func(ctx context.Context) error {
+		cur := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if cur <= m || atomic.CompareAndSwapInt32(&max, m, cur) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&cnt, 1)
+		return nil
+	}
+
+	g := NewGroup(context.Background(), 3, false)
+	for x := 0; x < 20; x++ {
+		g.Go(f)
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	if cnt != 20 {
+		t.Errorf("expected 20 runs, got %d", cnt)
+	}
+	if max > 3 {
+		t.Errorf("expected at most 3 running at same time, got %d", max)
+	}
+}
+
+func TestGroupFailFast(t *testing.T) {
+	theErr := errors.New("the error")
+	cases := []struct {
+		failFast bool
+		canceled int32
+	}{
+		{failFast: true, canceled: 3},
+		{failFast: false, canceled: 0},
+	}
+
+	for _, c := range cases {
+		var canceled, cnt int32
+		wg := &sync.WaitGroup{}
+		wg.Add(2)
+		failed := make(chan struct{})
+		// waits for cancellation if failFast, or the failed one returns
+		waiter := func(ctx context.Context) error {
+			defer atomic.AddInt32(&cnt, 1)
+			if c.failFast {
+				<-ctx.Done()
+			} else {
+				<-failed
+			}
+			if ctx.Err() != nil {
+				atomic.AddInt32(&canceled, 1)
+			}
+			return nil
+		}
+
+		err := TilErrAsyncLimit(
+			context.Background(), 3, c.failFast,
+			func(ctx context.Context) error {
+				wg.Done()
+				return waiter(ctx)
+			},
+			func(ctx context.Context) error {
+				wg.Done()
+				return waiter(ctx)
+			},
+			func(ctx context.Context) error {
+				wg.Wait()
+				atomic.AddInt32(&cnt, 1)
+				close(failed)
+				return theErr
+			},
+			// started after first error
+			waiter,
+		)
+		if err != theErr {
+			t.Fatal("unexpected error: ", err)
+		}
+		if cnt != 4 {
+			t.Errorf("failFast=%v: expected all funcs executed, got %d", c.failFast, cnt)
+		}
+		if canceled != c.canceled {
+			t.Errorf("failFast=%v: unexpected number of cancelled funcs: %d", c.failFast, canceled)
+		}
+	}
+}
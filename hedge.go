@@ -0,0 +1,109 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"time"
+)
+
+// Hedge creates a function which runs several copies of f to cut tail latency
+//
+// It is much like TilErrAsync, but for idempotent calls like reading:
+//
+//    - first copy starts immediately
+//    - another copy starts if no copy succeeds within delay, or right after a copy
+//      fails
+//    - at most maxInFlight copies are started, maxInFlight < 1 is treated as 1
+//    - it returns on first success, and the context passed to other copies is
+//      cancelled, but it does not wait them to return
+//    - error of last copy is returned if all copies failed
+//
+// ctx.Err() is returned if ctx is done before any copy succeeds.
+//
+//    lookup := Hedge(50*time.Millisecond, 3, func(ctx context.Context) error {
+//        return queryReplica(ctx, key)
+//    })
+//    err := lookup(ctx)
+//
+// Supported options: WithClock, WithObserver, WithName
+func Hedge(delay time.Duration, maxInFlight int, f func(ctx context.Context) error, opts ...Option) func(ctx context.Context) error {
+	g := HedgeValue(delay, maxInFlight, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, f(ctx)
+	}, opts...)
+	return func(ctx context.Context) error {
+		_, err := g(ctx)
+		return err
+	}
+}
+
+// HedgeValue is identical to Hedge, but f returns a value
+//
+// Value of first successful copy is returned.
+//
+// Supported options: WithClock, WithObserver, WithName
+func HedgeValue[T any](delay time.Duration, maxInFlight int, f func(ctx context.Context) (T, error), opts ...Option) func(ctx context.Context) (T, error) {
+	o := newOptions(opts)
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+
+	type result struct {
+		v   T
+		err error
+	}
+	return func(parent context.Context) (ret T, err error) {
+		ctx, cancel := context.WithCancel(parent)
+		defer cancel()
+
+		// buffered, so copies which are still running won't block
+		ch := make(chan result, maxInFlight)
+		var (
+			started, running int
+			timer            <-chan time.Time // for next copy
+			stop             = func() {}
+		)
+		defer func() { stop() }()
+		launch := func() {
+			go func(idx uint64) {
+				var r result
+				r.err = o.observe(idx, func() (err error) {
+					r.v, err = f(ctx)
+					return
+				})
+				ch <- r
+			}(o.next())
+			started++
+			running++
+
+			stop()
+			timer, stop = nil, func() {}
+			if started < maxInFlight {
+				timer, stop = newTimer(o.clock, delay)
+			}
+		}
+
+		launch()
+		for running > 0 {
+			select {
+			case <-ctx.Done():
+				return ret, ctx.Err()
+			case r := <-ch:
+				running--
+				if r.err == nil {
+					return r.v, nil
+				}
+				err = r.err
+				if started < maxInFlight {
+					launch()
+				}
+			case <-timer:
+				launch()
+			}
+		}
+
+		return
+	}
+}
@@ -0,0 +1,37 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+func ExampleHedgeValue() {
+	replicas := []time.Duration{
+		time.Second,          // the slow one
+		5 * time.Millisecond, // started at 10ms, done at 15ms
+	}
+	var cnt int32
+
+	lookup := HedgeValue(10*time.Millisecond, 2, func(ctx context.Context) (string, error) {
+		idx := atomic.AddInt32(&cnt, 1) - 1
+		d := replicas[idx]
+		name := fmt.Sprintf("replica #%d", idx+1)
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(d):
+			return name, nil
+		}
+	})
+
+	fmt.Println(lookup(context.Background()))
+
+	// output: replica #2 <nil>
+}
@@ -0,0 +1,95 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+func TestHedgeDelay(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	var cnt int32
+	cancelled := make(chan struct{})
+	f := HedgeValue(time.Second, 3, func(ctx context.Context) (int, error) {
+		idx := atomic.AddInt32(&cnt, 1)
+		if idx == 1 {
+			// slow one
+			<-ctx.Done()
+			close(cancelled)
+			return 0, ctx.Err()
+		}
+		return int(idx), nil
+	}, WithClock(c))
+
+	done := make(chan error)
+	var v int
+	go func() {
+		var err error
+		v, err = f(context.Background())
+		done <- err
+	}()
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+
+	if err := <-done; err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if v != 2 {
+		t.Fatal("expected result of second copy, got ", v)
+	}
+	<-cancelled
+	if n := atomic.LoadInt32(&cnt); n != 2 {
+		t.Fatal("expected 2 copies, got ", n)
+	}
+}
+
+func TestHedgeFailed(t *testing.T) {
+	var cnt int32
+	f := Hedge(time.Hour, 3, func(ctx context.Context) error {
+		return fmt.Errorf("#%d", atomic.AddInt32(&cnt, 1))
+	})
+
+	// failed copy starts next one immediately
+	err := f(context.Background())
+	if n := atomic.LoadInt32(&cnt); n != 3 {
+		t.Fatal("expected 3 copies, got ", n)
+	}
+	if err == nil || err.Error() != "#3" {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	atomic.StoreInt32(&cnt, 0)
+	Hedge(time.Hour, 0, func(ctx context.Context) error {
+		atomic.AddInt32(&cnt, 1)
+		return errors.New("")
+	})(context.Background())
+	if n := atomic.LoadInt32(&cnt); n != 1 {
+		t.Fatal("expected 1 copy, got ", n)
+	}
+}
+
+func TestHedgeCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	f := Hedge(time.Hour, 2, func(context.Context) error {
+		<-release
+		return nil
+	})
+
+	done := make(chan error)
+	go func() { done <- f(ctx) }()
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatal("unexpected error: ", err)
+	}
+}
@@ -137,12 +137,25 @@ func AnyErr(ctrls ...InfiniteLoopControl) (ret InfiniteLoopControl) {
 //
 // There are few things you should take care of:
 //
-//    - It will not interrupt current loop.
+//    - It will not interrupt current loop. Use InfiniteLoopCtx if you need it.
 //    - It will not wait any second between tasks.
 //
 // Common usecase is InfiniteLoop(RunAtLeast(someDuration, task))
 func InfiniteLoop(task func() error) (ret InfiniteLoopControl) {
-	ctx, cancel := context.WithCancel(context.Background())
+	return InfiniteLoopCtx(context.Background(), func(_ context.Context) error {
+		return task()
+	})
+}
+
+// InfiniteLoopCtx is identical to InfiniteLoop, but ctx is passed to your task
+//
+// ctx is derived from parent and is cancelled by Cancel(), so a task blocked in
+// long operation (network read for example) can be interrupted. Error returned by
+// task is sent to Err as-is, even if it is caused by cancelling.
+//
+// Loop also stops when parent is done, ctx.Err() is sent to Err in that case.
+func InfiniteLoopCtx(parent context.Context, task func(ctx context.Context) error) (ret InfiniteLoopControl) {
+	ctx, cancel := context.WithCancel(parent)
 	err := make(chan error)
 	go doInfiniteLooping(ctx, err, task)
 
@@ -152,17 +165,58 @@ func InfiniteLoop(task func() error) (ret InfiniteLoopControl) {
 	}
 }
 
-func doInfiniteLooping(ctx context.Context, errchan chan error, task func() error) {
-	var err error
+// InfiniteLoopOpts is identical to InfiniteLoop, but accepts options
+//
+// Every iteration is reported as an attempt. If the Observer implements
+// LoopObserver, start and stop of the loop are reported too.
+//
+// Supported options: WithClock, WithObserver, WithName
+func InfiniteLoopOpts(task func() error, opts ...Option) (ret InfiniteLoopControl) {
+	return InfiniteLoopCtxOpts(context.Background(), func(_ context.Context) error {
+		return task()
+	}, opts...)
+}
+
+// InfiniteLoopCtxOpts is identical to InfiniteLoopCtx, but accepts options
+//
+// See InfiniteLoopOpts for what is reported.
+//
+// Supported options: WithClock, WithObserver, WithName
+func InfiniteLoopCtxOpts(parent context.Context, task func(ctx context.Context) error, opts ...Option) (ret InfiniteLoopControl) {
+	o := newOptions(opts)
+	ctx, cancel := context.WithCancel(parent)
+	err := make(chan error)
+	go func() {
+		begin := o.clock.Now()
+		o.loopStarted()
+		e := loopTilErr(ctx, func(ctx context.Context) error {
+			return o.observe(o.next(), func() error { return task(ctx) })
+		})
+		o.loopStopped(begin, e)
+
+		err <- e
+		close(err)
+	}()
+
+	return InfiniteLoopControl{
+		Cancel: cancel,
+		Err:    err,
+	}
+}
+
+func doInfiniteLooping(ctx context.Context, errchan chan error, task func(context.Context) error) {
+	errchan <- loopTilErr(ctx, task)
+	close(errchan)
+}
+
+func loopTilErr(ctx context.Context, task func(context.Context) error) (err error) {
 	for err == nil {
 		select {
 		case <-ctx.Done():
 			err = ctx.Err()
 		default:
-			err = task()
+			err = task(ctx)
 		}
 	}
-
-	errchan <- err
-	close(errchan)
+	return
 }
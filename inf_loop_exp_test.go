@@ -5,6 +5,7 @@
 package routines
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -98,3 +99,29 @@ func ExampleAnyErr() {
 	// banned
 	// done2
 }
+
+func ExampleInfiniteLoopCtx() {
+	// a reader blocks until data arrives
+	data := make(chan string)
+	read := func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case str := <-data:
+			fmt.Println(str)
+			return nil
+		}
+	}
+
+	ctrl := InfiniteLoopCtx(context.Background(), read)
+	data <- "hello"
+	data <- "world"
+
+	// interrupts the reader which is waiting for data
+	ctrl.Cancel()
+	fmt.Println(<-ctrl.Err)
+
+	// output: hello
+	// world
+	// context canceled
+}
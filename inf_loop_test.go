@@ -164,3 +164,51 @@ func TestAllErrWithCancel(t *testing.T) {
 		)
 	}
 }
+
+func TestInfiniteLoopCtxInterrupt(t *testing.T) {
+	started := make(chan struct{})
+	task := func(ctx context.Context) error {
+		close(started)
+		// blocks until cancelled, like a long network read
+		<-ctx.Done()
+		return errFailed("interrupted")
+	}
+
+	ctrl := InfiniteLoopCtx(context.Background(), task)
+	<-started
+	ctrl.Cancel()
+
+	select {
+	case err := <-ctrl.Err:
+		if err != errFailed("interrupted") {
+			t.Fatal("unexpected error: ", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("task is not interrupted by Cancel()")
+	}
+	if _, ok := <-ctrl.Err; ok {
+		t.Fatal("expected Err to be closed")
+	}
+}
+
+func TestInfiniteLoopCtxParent(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	cnt := 0
+	task := func(ctx context.Context) error {
+		cnt++
+		if cnt == 3 {
+			cancel()
+		}
+		return nil
+	}
+
+	ctrl := InfiniteLoopCtx(parent, task)
+	defer ctrl.Cancel()
+
+	if err := <-ctrl.Err; err != context.Canceled {
+		t.Fatal("unexpected error: ", err)
+	}
+	if cnt != 3 {
+		t.Fatalf("expected run 3 times, actually run %d times", cnt)
+	}
+}
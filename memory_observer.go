@@ -0,0 +1,207 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"expvar"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBounds is used by NewMemoryObserver if no bound is given
+var DefaultLatencyBounds = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram counts durations into buckets
+//
+// Counts[i] is number of durations <= Bounds[i] (and > Bounds[i-1]), and last
+// element of Counts is number of durations > every bound.
+type Histogram struct {
+	Bounds []time.Duration
+	Counts []uint64
+}
+
+func newHistogram(bounds []time.Duration) Histogram {
+	return Histogram{
+		Bounds: bounds,
+		Counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+func (h Histogram) add(d time.Duration) {
+	idx := sort.Search(len(h.Bounds), func(i int) bool {
+		return d <= h.Bounds[i]
+	})
+	h.Counts[idx]++
+}
+
+func (h Histogram) clone() Histogram {
+	return Histogram{
+		Bounds: h.Bounds,
+		Counts: append([]uint64(nil), h.Counts...),
+	}
+}
+
+// Total returns number of durations in the histogram
+func (h Histogram) Total() (ret uint64) {
+	for _, c := range h.Counts {
+		ret += c
+	}
+	return
+}
+
+// Quantile estimates q-quantile (0 <= q <= 1) as upper bound of the bucket
+//
+// Durations greater than every bound are estimated as the largest bound. It
+// returns 0 if the histogram is empty.
+func (h Histogram) Quantile(q float64) time.Duration {
+	total := h.Total()
+	if total == 0 || len(h.Bounds) == 0 {
+		return 0
+	}
+
+	rank := uint64(q * float64(total))
+	if rank == 0 {
+		rank = 1
+	}
+	var cnt uint64
+	for idx, c := range h.Counts[:len(h.Bounds)] {
+		cnt += c
+		if cnt >= rank {
+			return h.Bounds[idx]
+		}
+	}
+	return h.Bounds[len(h.Bounds)-1]
+}
+
+// ObserverStats is statistics of a named helper collected by MemoryObserver
+type ObserverStats struct {
+	Started  uint64
+	Finished uint64
+	// number of finished executions which return non-nil error
+	Failed    uint64
+	Dropped   uint64
+	Waited    uint64
+	WaitTotal time.Duration
+	// execution time of finished executions
+	Latency Histogram
+}
+
+// MemoryObserver is an Observer which keeps counters and latency histograms in
+// memory, grouped by Event.Name
+//
+//    ob := NewMemoryObserver()
+//    ob.Publish("routines")
+//    f = OnceAtMostOpts(time.Second, f, WithObserver(ob), WithName("fetch"))
+type MemoryObserver struct {
+	lock   sync.Mutex
+	bounds []time.Duration
+	stats  map[string]*ObserverStats
+}
+
+// NewMemoryObserver creates a MemoryObserver
+//
+// bounds are upper bounds of latency buckets in ascending order, default to
+// DefaultLatencyBounds.
+func NewMemoryObserver(bounds ...time.Duration) *MemoryObserver {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBounds
+	}
+	return &MemoryObserver{
+		bounds: append([]time.Duration(nil), bounds...),
+		stats:  map[string]*ObserverStats{},
+	}
+}
+
+// with must be called with lock held
+func (m *MemoryObserver) with(name string) *ObserverStats {
+	s, ok := m.stats[name]
+	if !ok {
+		s = &ObserverStats{Latency: newHistogram(m.bounds)}
+		m.stats[name] = s
+	}
+	return s
+}
+
+// Started implements Observer
+func (m *MemoryObserver) Started(e Event) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.with(e.Name).Started++
+}
+
+// Finished implements Observer
+func (m *MemoryObserver) Finished(e Event) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	s := m.with(e.Name)
+	s.Finished++
+	if e.Err != nil {
+		s.Failed++
+	}
+	s.Latency.add(e.Duration)
+}
+
+// Waited implements Observer
+func (m *MemoryObserver) Waited(e Event) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	s := m.with(e.Name)
+	s.Waited++
+	s.WaitTotal += e.Duration
+}
+
+// Dropped implements Observer
+func (m *MemoryObserver) Dropped(e Event) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.with(e.Name).Dropped++
+}
+
+// Stats returns a copy of statistics of the named helper
+func (m *MemoryObserver) Stats(name string) (ret ObserverStats) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	s, ok := m.stats[name]
+	if !ok {
+		return ObserverStats{Latency: newHistogram(m.bounds)}
+	}
+	ret = *s
+	ret.Latency = s.Latency.clone()
+	return
+}
+
+// Snapshot returns a copy of statistics of all helpers
+func (m *MemoryObserver) Snapshot() (ret map[string]ObserverStats) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	ret = make(map[string]ObserverStats, len(m.stats))
+	for name, s := range m.stats {
+		c := *s
+		c.Latency = s.Latency.clone()
+		ret[name] = c
+	}
+	return
+}
+
+// Publish exports Snapshot() as an expvar variable
+//
+// Like expvar.Publish, it panics if name is already registered. Durations are
+// exported in nanoseconds.
+func (m *MemoryObserver) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return m.Snapshot()
+	}))
+}
@@ -0,0 +1,194 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import "time"
+
+// Middleware wraps a function into another function
+//
+// Adapters of helpers in this package are named after the helper, like AtLeast
+// for RunAtLeast and Within for OnceWithin. OnceAtMost and OnceSuccessAtMost are
+// exceptions: they're adapted as Spaced and SuccessSpaced, since "at most" means
+// timeout in RunAtMost.
+//
+// Helpers which keep state (OnceAtMost, RateLimit, ...) create new state every
+// time the Middleware is applied, so same Middleware can be applied to several
+// functions independently. Use Limited, AdaptiveLimited, Bulkheaded and Breaker
+// if you need to share the state.
+//
+// Recorded is not adapted as it wraps a function of different shape.
+type Middleware func(f func() error) func() error
+
+// Chain combines several Middleware in declared order
+//
+// First Middleware is the outermost one, so Chain(a, b, c)(f) equals to
+// a(b(c(f))).
+//
+//    // waits for rate limiter, and retries 3 times, each attempt costs at least 1s
+//    policy := Chain(
+//        RateLimited(time.Second, 1),
+//        Tries(3),
+//        AtLeast(time.Second),
+//    )
+//    err := policy(callAPI)()
+func Chain(mws ...Middleware) Middleware {
+	return func(f func() error) func() error {
+		for idx := len(mws) - 1; idx >= 0; idx-- {
+			f = mws[idx](f)
+		}
+		return f
+	}
+}
+
+// AtLeast adapts RunAtLeastOpts to Middleware
+func AtLeast(dur time.Duration, opts ...Option) Middleware {
+	return func(f func() error) func() error {
+		return RunAtLeastOpts(dur, f, opts...)
+	}
+}
+
+// SuccessAtLeast adapts RunSuccessAtLeastOpts to Middleware
+func SuccessAtLeast(dur time.Duration, opts ...Option) Middleware {
+	return func(f func() error) func() error {
+		return RunSuccessAtLeastOpts(dur, f, opts...)
+	}
+}
+
+// FailedAtLeast adapts RunFailedAtLeastOpts to Middleware
+func FailedAtLeast(dur time.Duration, opts ...Option) Middleware {
+	return func(f func() error) func() error {
+		return RunFailedAtLeastOpts(dur, f, opts...)
+	}
+}
+
+// Spaced adapts OnceAtMostOpts to Middleware
+func Spaced(dur time.Duration, opts ...Option) Middleware {
+	return func(f func() error) func() error {
+		return OnceAtMostOpts(dur, f, opts...)
+	}
+}
+
+// SuccessSpaced adapts OnceSuccessAtMostOpts to Middleware
+func SuccessSpaced(dur time.Duration, opts ...Option) Middleware {
+	return func(f func() error) func() error {
+		return OnceSuccessAtMostOpts(dur, f, opts...)
+	}
+}
+
+// Within adapts OnceWithinOpts to Middleware
+func Within(dur time.Duration, opts ...Option) Middleware {
+	return func(f func() error) func() error {
+		return OnceWithinOpts(dur, f, opts...)
+	}
+}
+
+// SuccessWithin adapts OnceSuccessWithinOpts to Middleware
+func SuccessWithin(dur time.Duration, opts ...Option) Middleware {
+	return func(f func() error) func() error {
+		return OnceSuccessWithinOpts(dur, f, opts...)
+	}
+}
+
+// WithinTrailing adapts OnceWithinTrailing to Middleware
+func WithinTrailing(dur time.Duration, opts ...Option) Middleware {
+	return func(f func() error) func() error {
+		return OnceWithinTrailing(dur, f, opts...)
+	}
+}
+
+// Debounced adapts Debounce to Middleware
+func Debounced(dur time.Duration, opts ...Option) Middleware {
+	return DebouncedMax(dur, 0, opts...)
+}
+
+// DebouncedMax adapts DebounceMax to Middleware
+func DebouncedMax(dur, maxWait time.Duration, opts ...Option) Middleware {
+	return func(f func() error) func() error {
+		return DebounceMax(dur, maxWait, f, opts...)
+	}
+}
+
+// RateLimited adapts RateLimit to Middleware
+func RateLimited(every time.Duration, burst int, opts ...Option) Middleware {
+	return func(f func() error) func() error {
+		return RateLimit(every, burst, f, opts...)
+	}
+}
+
+// Limited adapts l.Wrap to Middleware, functions wrapped by it share l
+func Limited(l *Limiter) Middleware {
+	return l.Wrap
+}
+
+// AdaptiveLimited adapts l.Wrap to Middleware, functions wrapped by it share l
+func AdaptiveLimited(l *AdaptiveLimiter) Middleware {
+	return l.Wrap
+}
+
+// Bulkheaded adapts b.Wrap to Middleware, functions wrapped by it share slots of b
+func Bulkheaded(b Bulkhead) Middleware {
+	return b.Wrap
+}
+
+// Breaker adapts cb.Wrap to Middleware, functions wrapped by it share cb
+func Breaker(cb *CircuitBreaker) Middleware {
+	return cb.Wrap
+}
+
+// Recovered adapts Recover to Middleware
+//
+// Retry-like helpers run f in another goroutine, so put Recovered after them to
+// catch the panic.
+func Recovered() Middleware {
+	return Recover
+}
+
+// Observed adapts Observe to Middleware
+func Observed(opts ...Option) Middleware {
+	return func(f func() error) func() error {
+		return Observe(f, opts...)
+	}
+}
+
+// Retried adapts Retry to Middleware
+//
+// Wrapped function blocks until f succeeds, errors of failed attempts are
+// dropped. Use it with Observed or WithLogger if you need them. If Retry gives up
+// without success as f returns a PermanentError, it is returned.
+func Retried() Middleware {
+	return func(f func() error) func() error {
+		return func() (err error) {
+			ok := false
+			for err = range Retry(func() error {
+				e := f()
+				ok = e == nil
+				return e
+			}) {
+			}
+			if ok {
+				return nil
+			}
+			return
+		}
+	}
+}
+
+// Tries adapts TryAtMost to Middleware
+func Tries(n uint64) Middleware {
+	return func(f func() error) func() error {
+		return func() error {
+			return TryAtMost(n, f)
+		}
+	}
+}
+
+// TriesWithBackoff adapts TryAtMostWithBackoff to Middleware
+func TriesWithBackoff(n uint64, b Backoff, opts ...Option) Middleware {
+	return func(f func() error) func() error {
+		return func() error {
+			return TryAtMostWithBackoff(n, b, f, opts...)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+func ExampleChain() {
+	policy := Chain(
+		RateLimited(time.Millisecond, 1),
+		Tries(3),
+		FailedAtLeast(time.Millisecond),
+	)
+
+	cnt := 0
+	callAPI := policy(func() error {
+		cnt++
+		fmt.Println("attempt", cnt)
+		return errors.New("server is busy")
+	})
+	fmt.Println(callAPI())
+
+	// output: attempt 1
+	// attempt 2
+	// attempt 3
+	// server is busy
+}
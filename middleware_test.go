@@ -0,0 +1,134 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+func TestChainOrder(t *testing.T) {
+	var trace []string
+	mw := func(name string) Middleware {
+		return func(f func() error) func() error {
+			return func() error {
+				trace = append(trace, name)
+				return f()
+			}
+		}
+	}
+
+	Chain(mw("a"), mw("b"), mw("c"))(func() error {
+		trace = append(trace, "f")
+		return nil
+	})()
+	if len(trace) != 4 || trace[0] != "a" || trace[1] != "b" || trace[2] != "c" || trace[3] != "f" {
+		t.Fatalf("unexpected order: %v", trace)
+	}
+
+	if err := Chain()(func() error { return nil })(); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+}
+
+func TestChainPolicy(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	theErr := errors.New("")
+	ob := NewMemoryObserver()
+	policy := Chain(
+		Tries(3),
+		Observed(WithObserver(ob)),
+		Recovered(),
+		FailedAtLeast(time.Second, WithClock(c)),
+	)
+
+	cnt := 0
+	done := make(chan error)
+	go func() {
+		done <- policy(func() error {
+			if cnt++; cnt == 3 {
+				panic("boom")
+			}
+			return theErr
+		})()
+	}()
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+
+	var pe *PanicError
+	if err := <-done; !errors.As(err, &pe) {
+		t.Fatal("unexpected error: ", err)
+	}
+	if s := ob.Stats(""); s.Finished != 3 || s.Failed != 3 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
+
+func TestMiddlewareState(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	cnt := 0
+	f := func() error {
+		cnt++
+		return nil
+	}
+
+	// every application has its own state
+	within := Within(time.Second, WithClock(c))
+	a, b := within(f), within(f)
+	a()
+	a()
+	b()
+	if cnt != 2 {
+		t.Fatal("expected run twice, got ", cnt)
+	}
+
+	// shared limiter
+	lim := Limited(NewLimiter(time.Second, 1, WithClock(c)))
+	a, b = lim(f), lim(f)
+	a()
+	done := make(chan error)
+	go func() { done <- b() }()
+	c.BlockUntil(1)
+	if cnt != 3 {
+		t.Fatal("expected run 3 times, got ", cnt)
+	}
+	c.Advance(time.Second)
+	<-done
+	if cnt != 4 {
+		t.Fatal("expected run 4 times, got ", cnt)
+	}
+}
+
+func TestRetried(t *testing.T) {
+	cnt := 0
+	err := Retried()(func() error {
+		if cnt++; cnt < 3 {
+			return errors.New("")
+		}
+		return nil
+	})()
+	if err != nil || cnt != 3 {
+		t.Fatalf("unexpected result: %d, %v", cnt, err)
+	}
+}
+
+func TestRetriedPermanent(t *testing.T) {
+	theErr := errors.New("bad")
+	cnt := 0
+	err := Chain(Retried())(func() error {
+		if cnt++; cnt < 3 {
+			return errors.New("")
+		}
+		return Permanent(theErr)
+	})()
+	if !errors.Is(err, theErr) || !IsPermanent(err) || cnt != 3 {
+		t.Fatalf("unexpected result: %d, %v", cnt, err)
+	}
+}
@@ -0,0 +1,60 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IndexedError records which function (by its index in arguments) produced Err
+type IndexedError struct {
+	Index int
+	Err   error
+}
+
+func (e *IndexedError) Error() string {
+	return fmt.Sprintf("#%d: %v", e.Index, e.Err)
+}
+
+func (e *IndexedError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError is a list of errors produced by several functions, sorted by index
+//
+// errors.Is and errors.As match against every member.
+type MultiError []*IndexedError
+
+func (e MultiError) Error() string {
+	strs := make([]string, len(e))
+	for idx, x := range e {
+		strs[idx] = x.Error()
+	}
+	return strings.Join(strs, "; ")
+}
+
+func (e MultiError) Unwrap() []error {
+	ret := make([]error, len(e))
+	for idx, x := range e {
+		ret[idx] = x
+	}
+	return ret
+}
+
+// collectErrors builds a MultiError from errs, nil is returned if all are nil
+func collectErrors(errs []error) error {
+	var ret MultiError
+	for idx, e := range errs {
+		if e != nil {
+			ret = append(ret, &IndexedError{Index: idx, Err: e})
+		}
+	}
+
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
@@ -0,0 +1,151 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"time"
+)
+
+// Event describes what happens in a helper, passed to Observer
+type Event struct {
+	// Name given by WithName().
+	Name string
+	// Index (zero-based) of the call to the helper.
+	Attempt uint64
+	// Execution time in Finished(), or waiting time in Waited().
+	Duration time.Duration
+	// Result of the call in Finished().
+	Err error
+}
+
+// Observer receives events from helpers configured by WithObserver()
+//
+//   - Started and Finished are reported around every execution of your function
+//   - Waited is reported when a call is delayed, like OnceAtMost() and backoff
+//     between retries
+//   - Dropped is reported when a call does not trigger its own execution, like
+//     ignored calls of OnceWithin(), merged calls of Debounce() and rejected calls
+//     of CircuitBreaker
+//
+// Observer is called synchronously, so it must be fast and thread-safe.
+type Observer interface {
+	Started(e Event)
+	Finished(e Event)
+	Waited(e Event)
+	Dropped(e Event)
+}
+
+// LoopObserver is an optional interface of Observer
+//
+// If the Observer passed to InfiniteLoopOpts() implements it, LoopStarted is
+// reported when the loop starts, and LoopStopped is reported with running time
+// and the error which stops the loop. Event.Attempt in LoopStopped is number of
+// iterations.
+type LoopObserver interface {
+	LoopStarted(e Event)
+	LoopStopped(e Event)
+}
+
+type nopObserver struct{}
+
+func (nopObserver) Started(Event)  {}
+func (nopObserver) Finished(Event) {}
+func (nopObserver) Waited(Event)   {}
+func (nopObserver) Dropped(Event)  {}
+
+// WithObserver reports events to ob, nil means reporting nothing (default)
+func WithObserver(ob Observer) Option {
+	return func(o *options) {
+		if ob == nil {
+			ob = nopObserver{}
+		}
+		o.observer = ob
+	}
+}
+
+// WithName sets Event.Name, so an Observer can tell which helper reports it
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// observe calls f and reports Started and Finished
+func (o *options) observe(attempt uint64, f func() error) (err error) {
+	o.observer.Started(Event{Name: o.name, Attempt: attempt})
+	begin := o.clock.Now()
+	err = f()
+	o.observer.Finished(Event{
+		Name:     o.name,
+		Attempt:  attempt,
+		Duration: o.clock.Now().Sub(begin),
+		Err:      err,
+	})
+	return
+}
+
+// sleep waits for d and reports Waited
+func (o *options) sleep(attempt uint64, d time.Duration) {
+	o.clock.Sleep(d)
+	o.waited(attempt, d)
+}
+
+func (o *options) waited(attempt uint64, d time.Duration) {
+	if d > 0 {
+		o.observer.Waited(Event{Name: o.name, Attempt: attempt, Duration: d})
+	}
+}
+
+func (o *options) dropped(attempt uint64) {
+	o.observer.Dropped(Event{Name: o.name, Attempt: attempt})
+}
+
+func (o *options) loopStarted() {
+	if lo, ok := o.observer.(LoopObserver); ok {
+		lo.LoopStarted(Event{Name: o.name})
+	}
+}
+
+func (o *options) loopStopped(begin time.Time, err error) {
+	if lo, ok := o.observer.(LoopObserver); ok {
+		lo.LoopStopped(Event{
+			Name:     o.name,
+			Attempt:  o.calls.Load(),
+			Duration: o.clock.Now().Sub(begin),
+			Err:      err,
+		})
+	}
+}
+
+// Observe creates a function which reports Started and Finished around f
+//
+// It is used with helpers which do not accept options:
+//
+//    ob := NewMemoryObserver()
+//    ch := Retry(Observe(f, WithObserver(ob), WithName("fetch")))
+//    ctrl := InfiniteLoop(Observe(task, WithObserver(ob), WithName("task")))
+//
+// Errors are classified before reporting if WithClassifier is given.
+//
+// Supported options: WithClock, WithObserver, WithName, WithClassifier
+func Observe(f func() error, opts ...Option) func() error {
+	o := newOptions(opts)
+	f = o.classify(f)
+	return func() error {
+		return o.observe(o.next(), f)
+	}
+}
+
+// ObserveCtx is identical to Observe, but for context-aware functions like those
+// passed to InfiniteLoopCtx
+//
+// Supported options: WithClock, WithObserver, WithName
+func ObserveCtx(f func(ctx context.Context) error, opts ...Option) func(ctx context.Context) error {
+	o := newOptions(opts)
+	return func(ctx context.Context) error {
+		return o.observe(o.next(), func() error { return f(ctx) })
+	}
+}
@@ -0,0 +1,26 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"fmt"
+	"time"
+)
+
+func ExampleMemoryObserver() {
+	ob := NewMemoryObserver()
+	f := OnceWithinOpts(time.Minute, func() error {
+		return nil
+	}, WithObserver(ob), WithName("fetch"))
+
+	for i := 0; i < 3; i++ {
+		f()
+	}
+
+	s := ob.Stats("fetch")
+	fmt.Printf("executed %d times, dropped %d times", s.Finished, s.Dropped)
+
+	// output: executed 1 times, dropped 2 times
+}
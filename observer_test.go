@@ -0,0 +1,183 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+var _ Observer = (*MemoryObserver)(nil)
+
+func TestObserverOnceAtMost(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	ob := NewMemoryObserver()
+	f := OnceAtMostOpts(time.Second, func() error {
+		c.Advance(10 * time.Millisecond)
+		return nil
+	}, WithClock(c), WithObserver(ob), WithName("f"))
+
+	f()
+	done := make(chan error)
+	go func() { done <- f() }()
+	c.BlockUntil(1)
+	c.Advance(990 * time.Millisecond)
+	<-done
+
+	s := ob.Stats("f")
+	if s.Started != 2 || s.Finished != 2 || s.Failed != 0 {
+		t.Fatalf("unexpected counters: %+v", s)
+	}
+	if s.Waited != 1 || s.WaitTotal != 990*time.Millisecond {
+		t.Fatalf("unexpected waiting: %d, %v", s.Waited, s.WaitTotal)
+	}
+	if q := s.Latency.Quantile(1); q != 10*time.Millisecond {
+		t.Fatalf("unexpected latency: %v", q)
+	}
+}
+
+func TestObserverOnceWithin(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	ob := NewMemoryObserver()
+	f := OnceWithinOpts(time.Second, func() error {
+		return nil
+	}, WithClock(c), WithObserver(ob), WithName("f"))
+
+	f()
+	f()
+	f()
+
+	s := ob.Stats("f")
+	if s.Started != 1 || s.Dropped != 2 {
+		t.Fatalf("unexpected counters: %+v", s)
+	}
+}
+
+func TestObserverBackoff(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	ob := NewMemoryObserver()
+	var attempts []uint64
+	rec := &recordObserver{Observer: ob, attempts: &attempts}
+
+	done := make(chan error)
+	go func() {
+		done <- TryAtMostWithBackoff(3, ConstantBackoff(time.Second), func() error {
+			return errors.New("")
+		}, WithClock(c), WithObserver(rec), WithName("retry"))
+	}()
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	if err := <-done; err == nil {
+		t.Fatal("expected error")
+	}
+
+	s := ob.Stats("retry")
+	if s.Finished != 3 || s.Failed != 3 || s.Waited != 2 || s.WaitTotal != 2*time.Second {
+		t.Fatalf("unexpected counters: %+v", s)
+	}
+	if len(attempts) != 3 || attempts[0] != 0 || attempts[2] != 2 {
+		t.Fatalf("unexpected attempts: %v", attempts)
+	}
+}
+
+type recordObserver struct {
+	Observer
+	attempts *[]uint64
+}
+
+func (r *recordObserver) Started(e Event) {
+	*r.attempts = append(*r.attempts, e.Attempt)
+	r.Observer.Started(e)
+}
+
+func TestObserve(t *testing.T) {
+	ob := NewMemoryObserver()
+	theErr := errors.New("")
+	f := Observe(func() error {
+		return theErr
+	}, WithObserver(ob), WithName("f"))
+	if err := TryAtMost(3, f); err != theErr {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	s := ob.Stats("f")
+	if s.Started != 3 || s.Finished != 3 || s.Failed != 3 {
+		t.Fatalf("unexpected counters: %+v", s)
+	}
+
+	// nil observer is ignored
+	f = Observe(func() error { return nil }, WithObserver(nil))
+	if err := f(); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram([]time.Duration{time.Millisecond, time.Second})
+	if q := h.Quantile(0.5); q != 0 {
+		t.Fatal("expected 0 for empty histogram, got ", q)
+	}
+
+	for _, d := range []time.Duration{time.Microsecond, time.Millisecond, time.Second, time.Minute} {
+		h.add(d)
+	}
+	if h.Counts[0] != 2 || h.Counts[1] != 1 || h.Counts[2] != 1 {
+		t.Fatalf("unexpected counts: %v", h.Counts)
+	}
+	if h.Total() != 4 {
+		t.Fatal("unexpected total: ", h.Total())
+	}
+	if q := h.Quantile(0.5); q != time.Millisecond {
+		t.Fatal("unexpected median: ", q)
+	}
+	if q := h.Quantile(1); q != time.Second {
+		t.Fatal("unexpected max: ", q)
+	}
+}
+
+func TestMemoryObserverPublish(t *testing.T) {
+	ob := NewMemoryObserver()
+	// expvar names cannot be reused, so it works with -count
+	name := fmt.Sprint("TestMemoryObserverPublish", time.Now().UnixNano())
+	ob.Publish(name)
+	ob.Dropped(Event{Name: "f"})
+
+	var v map[string]ObserverStats
+	err := json.Unmarshal([]byte(expvar.Get(name).String()), &v)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if v["f"].Dropped != 1 {
+		t.Fatalf("unexpected value: %+v", v)
+	}
+}
+
+func TestObserverInfiniteLoop(t *testing.T) {
+	ob := NewMemoryObserver()
+	theErr := errors.New("")
+	cnt := 0
+	ctrl := InfiniteLoopOpts(func() error {
+		if cnt++; cnt == 3 {
+			return theErr
+		}
+		return nil
+	}, WithObserver(ob))
+	if err := <-ctrl.Err; err != theErr {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	s := ob.Stats("")
+	if s.Started != 3 || s.Finished != 3 || s.Failed != 1 {
+		t.Fatalf("unexpected counters: %+v", s)
+	}
+}
@@ -0,0 +1,68 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Option configures optional behaviour of helpers which accept it
+//
+// Options which are meaningless to a helper are silently ignored.
+type Option func(*options)
+
+type options struct {
+	clock    Clock
+	observer Observer
+	name     string
+	loc      *time.Location
+
+	classifier Classifier
+
+	// time budget of retrying
+	maxElapsed time.Duration
+	deadline   time.Time
+	ctx        context.Context
+
+	// do not wait for timed out function, see WithAbandon
+	abandon bool
+
+	// queue of Bulkhead
+	queueSize    int
+	queueTimeout time.Duration
+
+	// number of calls to the helper, for Event.Attempt
+	calls atomic.Uint64
+}
+
+func newOptions(opts []Option) (ret *options) {
+	ret = &options{
+		clock:     RealClock(),
+		observer:  nopObserver{},
+		loc:       time.Local,
+		queueSize: -1,
+	}
+	for _, o := range opts {
+		o(ret)
+	}
+
+	return
+}
+
+// next returns index of current call to the helper
+func (o *options) next() uint64 {
+	return o.calls.Add(1) - 1
+}
+
+// WithClock replaces the clock used to measure time and to wait
+//
+// It is mostly used in tests, see subpackage routinestest.
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
@@ -0,0 +1,150 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"errors"
+	"net"
+)
+
+// PermanentError marks an error which should not be retried
+//
+// Retry, TriesAtMost and their variants stop immediately when f returns it. It
+// is sent to err channel (or returned by TryAtMost) as-is, use errors.Is or
+// errors.As to check the wrapped error.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err into PermanentError, nil is returned as-is
+//
+//    err := TryAtMost(5, func() error {
+//        resp, err := callAPI()
+//        if err != nil {
+//            return err
+//        }
+//        if resp.StatusCode == 400 {
+//            return Permanent(errBadRequest)
+//        }
+//        return nil
+//    })
+func Permanent(err error) error {
+	if err == nil || IsPermanent(err) {
+		return err
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports whether err is (or wraps) a PermanentError
+func IsPermanent(err error) bool {
+	var pe *PermanentError
+	return errors.As(err, &pe)
+}
+
+// Classifier reports whether err is permanent, err is never nil
+type Classifier func(err error) (permanent bool)
+
+// PermanentOn creates a Classifier which treats errors matching any of targets
+// (by errors.Is) as permanent
+func PermanentOn(targets ...error) Classifier {
+	return func(err error) bool {
+		return isAny(err, targets)
+	}
+}
+
+// RetryOn creates a Classifier which treats errors matching none of targets (by
+// errors.Is) as permanent
+func RetryOn(targets ...error) Classifier {
+	return func(err error) bool {
+		return !isAny(err, targets)
+	}
+}
+
+// RetryIf creates a Classifier which treats errors as permanent unless pred
+// returns true
+//
+//    // retries only on network timeout
+//    c := RetryIf(IsNetTemporary)
+func RetryIf(pred func(err error) bool) Classifier {
+	return func(err error) bool {
+		return !pred(err)
+	}
+}
+
+// AnyPermanent combines several Classifier, err is permanent if any of them says
+// so
+func AnyPermanent(cs ...Classifier) Classifier {
+	return func(err error) bool {
+		for _, c := range cs {
+			if c(err) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IsNetTemporary reports whether err is a net.Error which is timeout or temporary
+func IsNetTemporary(err error) bool {
+	var ne net.Error
+	if !errors.As(err, &ne) {
+		return false
+	}
+	// Temporary is deprecated, but still implemented by many errors
+	return ne.Timeout() || ne.Temporary()
+}
+
+func isAny(err error, targets []error) bool {
+	for _, t := range targets {
+		if errors.Is(err, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify creates a function which wraps error of f with Permanent if c says so
+//
+//    ch := Retry(Classify(PermanentOn(errBadRequest), callAPI))
+func Classify(c Classifier, f func() error) func() error {
+	return func() (err error) {
+		if err = f(); err != nil && c(err) {
+			err = Permanent(err)
+		}
+		return
+	}
+}
+
+// Classified adapts Classify to Middleware
+//
+// Put it after retry helpers like Tries in Chain, so errors are classified
+// before they are seen by the retry helper.
+func Classified(c Classifier) Middleware {
+	return func(f func() error) func() error {
+		return Classify(c, f)
+	}
+}
+
+// WithClassifier marks errors as permanent by c, see Classify
+func WithClassifier(c Classifier) Option {
+	return func(o *options) {
+		o.classifier = c
+	}
+}
+
+func (o *options) classify(f func() error) func() error {
+	if o.classifier == nil {
+		return f
+	}
+	return Classify(o.classifier, f)
+}
@@ -0,0 +1,126 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+func TestPermanent(t *testing.T) {
+	theErr := errors.New("the error")
+	if Permanent(nil) != nil {
+		t.Fatal("expected nil")
+	}
+
+	err := Permanent(theErr)
+	if !IsPermanent(err) || !errors.Is(err, theErr) || err.Error() != theErr.Error() {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if Permanent(err) != err {
+		t.Fatal("expected not to wrap twice")
+	}
+	if !IsPermanent(fmt.Errorf("wrapped: %w", err)) {
+		t.Fatal("expected wrapped error to be permanent")
+	}
+	if IsPermanent(theErr) {
+		t.Fatal("expected not permanent")
+	}
+}
+
+func TestPermanentStopsRetry(t *testing.T) {
+	theErr := errors.New("the error")
+	cnt := 0
+	f := func() error {
+		if cnt++; cnt == 2 {
+			return Permanent(theErr)
+		}
+		return errors.New("")
+	}
+
+	var errs []error
+	for e := range Retry(f) {
+		errs = append(errs, e)
+	}
+	if cnt != 2 || len(errs) != 2 || !errors.Is(errs[1], theErr) {
+		t.Fatalf("unexpected result: %d, %v", cnt, errs)
+	}
+
+	cnt = 0
+	if err := TryAtMost(5, f); cnt != 2 || !errors.Is(err, theErr) {
+		t.Fatalf("unexpected result: %d, %v", cnt, err)
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	timeout := &net.OpError{Op: "dial", Err: context.DeadlineExceeded}
+	refused := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	cases := []struct {
+		name   string
+		c      Classifier
+		err    error
+		expect bool
+	}{
+		{name: "PermanentOn match", c: PermanentOn(errA), err: fmt.Errorf("x: %w", errA), expect: true},
+		{name: "PermanentOn not match", c: PermanentOn(errA), err: errB},
+		{name: "RetryOn match", c: RetryOn(errA, errB), err: errB},
+		{name: "RetryOn not match", c: RetryOn(errA), err: errB, expect: true},
+		{name: "net timeout", c: RetryIf(IsNetTemporary), err: timeout},
+		{name: "net refused", c: RetryIf(IsNetTemporary), err: refused, expect: true},
+		{name: "not net", c: RetryIf(IsNetTemporary), err: errA, expect: true},
+		{name: "any", c: AnyPermanent(PermanentOn(errA), PermanentOn(errB)), err: errB, expect: true},
+		{name: "any none", c: AnyPermanent(PermanentOn(errA)), err: errB},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := c.c(c.err); actual != c.expect {
+				t.Fatalf("expected %v, got %v", c.expect, actual)
+			}
+		})
+	}
+}
+
+func TestWithClassifier(t *testing.T) {
+	errBad := errors.New("bad request")
+	cnt := 0
+	f := func() error {
+		if cnt++; cnt == 2 {
+			return errBad
+		}
+		return errors.New("timeout")
+	}
+
+	err := TryAtMostOpts(5, f, WithClassifier(PermanentOn(errBad)))
+	if cnt != 2 || !IsPermanent(err) || !errors.Is(err, errBad) {
+		t.Fatalf("unexpected result: %d, %v", cnt, err)
+	}
+
+	cnt = 0
+	c := routinestest.NewClock(time.Now())
+	done := make(chan error)
+	go func() {
+		done <- TryAtMostWithBackoff(5, ConstantBackoff(time.Second), f,
+			WithClock(c), WithClassifier(PermanentOn(errBad)))
+	}()
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	if err := <-done; cnt != 2 || !errors.Is(err, errBad) {
+		t.Fatalf("unexpected result: %d, %v", cnt, err)
+	}
+
+	cnt = 0
+	err = Chain(Tries(5), Classified(PermanentOn(errBad)))(f)()
+	if cnt != 2 || !IsPermanent(err) {
+		t.Fatalf("unexpected result: %d, %v", cnt, err)
+	}
+}
@@ -0,0 +1,187 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket rate limiter
+//
+// The bucket holds at most burst tokens, and is refilled by one token every
+// duration. Each call consumes one token. "100 calls per minute with bursts of 10"
+// can be written as
+//
+//    l := NewLimiter(time.Minute/100, 10)
+//
+// Unlike OnceAtMost, callers are not serialized: they are blocked only when the
+// bucket is empty. A Limiter can be shared by several functions, see Wrap().
+type Limiter struct {
+	lock   sync.Mutex
+	o      *options
+	every  time.Duration
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a Limiter with full bucket
+//
+// every <= 0 means no limit, and burst < 1 is treated as 1.
+//
+// Supported options: WithClock, WithObserver, WithName. Events are reported by
+// functions created by Wrap().
+func NewLimiter(every time.Duration, burst int, opts ...Option) (ret *Limiter) {
+	if burst < 1 {
+		burst = 1
+	}
+	o := newOptions(opts)
+	return &Limiter{
+		o:      o,
+		every:  every,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   o.clock.Now(),
+	}
+}
+
+// refill must be called with lock held
+func (l *Limiter) refill(now time.Time) {
+	if d := now.Sub(l.last); d > 0 {
+		l.tokens += float64(d) / float64(l.every)
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+	}
+}
+
+// Allow consumes a token and returns true if there is one, or returns false
+// without consuming anything
+func (l *Limiter) Allow() (ok bool) {
+	if l.every <= 0 {
+		return true
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.refill(l.o.clock.Now())
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Reserve consumes a token in advance, and tells you how long to wait until you
+// can act
+//
+// The token is consumed even if the bucket is empty. Call Cancel() on returned
+// Reservation if you decide not to act.
+func (l *Limiter) Reserve() (ret *Reservation) {
+	now := l.o.clock.Now()
+	ret = &Reservation{l: l, at: now}
+	if l.every <= 0 {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.refill(now)
+	l.tokens--
+	if l.tokens < 0 {
+		ret.at = now.Add(time.Duration(-l.tokens * float64(l.every)))
+	}
+	return
+}
+
+// Wait blocks until a token is available or ctx is done
+//
+// The token is returned to bucket if ctx is done before it is available.
+func (l *Limiter) Wait(ctx context.Context) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	r := l.Reserve()
+	d := r.Delay()
+	if d <= 0 {
+		return
+	}
+
+	timer, stop := newTimer(l.o.clock, d)
+	defer stop()
+	select {
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	case <-timer:
+		return
+	}
+}
+
+// Wrap creates a function which waits for a token before calling f
+//
+// Functions wrapped by same Limiter share the same budget.
+func (l *Limiter) Wrap(f func() error) func() error {
+	return func() error {
+		idx := l.o.next()
+		begin := l.o.clock.Now()
+		l.Wait(context.Background())
+		l.o.waited(idx, l.o.clock.Now().Sub(begin))
+		return l.o.observe(idx, f)
+	}
+}
+
+// Reservation is a token reserved by Limiter.Reserve()
+type Reservation struct {
+	l    *Limiter
+	at   time.Time
+	once sync.Once
+}
+
+// Delay returns how long to wait before acting, 0 means you can act now
+func (r *Reservation) Delay() (ret time.Duration) {
+	if ret = r.at.Sub(r.l.o.clock.Now()); ret < 0 {
+		ret = 0
+	}
+	return
+}
+
+// Cancel returns the token to bucket if it is not available yet
+//
+// It's safe to call Cancel multiple times, only first time is executed.
+func (r *Reservation) Cancel() {
+	if r.l.every <= 0 {
+		return
+	}
+
+	r.once.Do(func() {
+		l := r.l
+		l.lock.Lock()
+		defer l.lock.Unlock()
+
+		now := l.o.clock.Now()
+		if !now.Before(r.at) {
+			return
+		}
+		l.refill(now)
+		if l.tokens++; l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	})
+}
+
+// RateLimit creates a function which calls f at the rate of one call every
+// duration, with bursts of at most burst calls
+//
+// It is a shortcut of NewLimiter(every, burst, opts...).Wrap(f).
+//
+// Supported options: WithClock, WithObserver, WithName
+func RateLimit(every time.Duration, burst int, f func() error, opts ...Option) func() error {
+	return NewLimiter(every, burst, opts...).Wrap(f)
+}
@@ -0,0 +1,43 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"fmt"
+	"time"
+)
+
+func ExampleLimiter() {
+	// 100 requests per minute with bursts of 10, shared by two apis
+	l := NewLimiter(time.Minute/100, 10)
+	getUser := l.Wrap(func() error {
+		fmt.Println("user")
+		return nil
+	})
+	getPost := l.Wrap(func() error {
+		fmt.Println("post")
+		return nil
+	})
+
+	// first 10 calls are not blocked
+	for x := 0; x < 5; x++ {
+		getUser()
+		getPost()
+	}
+	// next call has to wait 0.6s
+	fmt.Println(l.Allow())
+
+	// output: user
+	// post
+	// user
+	// post
+	// user
+	// post
+	// user
+	// post
+	// user
+	// post
+	// false
+}
@@ -0,0 +1,129 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	l := NewLimiter(time.Second, 3, WithClock(c))
+
+	for x := 0; x < 3; x++ {
+		if !l.Allow() {
+			t.Fatalf("#%d: expected to be allowed in burst", x)
+		}
+	}
+	if l.Allow() {
+		t.Fatal("expected to be denied when bucket is empty")
+	}
+
+	c.Advance(1500 * time.Millisecond)
+	if !l.Allow() {
+		t.Fatal("expected to be allowed after refilled")
+	}
+	if l.Allow() {
+		t.Fatal("expected to be denied, only half token left")
+	}
+	c.Advance(500 * time.Millisecond)
+	if !l.Allow() {
+		t.Fatal("expected to be allowed after refilled")
+	}
+
+	// refilled no more than burst
+	c.Advance(time.Hour)
+	for x := 0; x < 3; x++ {
+		if !l.Allow() {
+			t.Fatalf("#%d: expected to be allowed in burst", x)
+		}
+	}
+	if l.Allow() {
+		t.Fatal("expected to be denied when bucket is empty")
+	}
+}
+
+func TestLimiterReserve(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	l := NewLimiter(time.Second, 1, WithClock(c))
+
+	for x := 0; x < 3; x++ {
+		expect := time.Duration(x) * time.Second
+		if d := l.Reserve().Delay(); d != expect {
+			t.Fatalf("#%d: expected %v, got %v", x, expect, d)
+		}
+	}
+
+	r := l.Reserve()
+	if d := r.Delay(); d != 3*time.Second {
+		t.Fatalf("expected 3s, got %v", d)
+	}
+	r.Cancel()
+	r.Cancel()
+	if d := l.Reserve().Delay(); d != 3*time.Second {
+		t.Fatalf("expected 3s after cancel, got %v", d)
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	l := NewLimiter(time.Second, 1, WithClock(c))
+
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	done := make(chan error)
+	go func() { done <- l.Wait(context.Background()) }()
+	c.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("expected to wait for a token")
+	default:
+	}
+	c.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	// cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { done <- l.Wait(ctx) }()
+	c.BlockUntil(1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatal("unexpected error: ", err)
+	}
+	// token is returned
+	if d := l.Reserve().Delay(); d != time.Second {
+		t.Fatalf("expected 1s, got %v", d)
+	}
+}
+
+func TestLimiterShared(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	l := NewLimiter(time.Second, 2, WithClock(c))
+	cnt := [2]int{}
+	f1 := l.Wrap(func() error { cnt[0]++; return nil })
+	f2 := l.Wrap(func() error { cnt[1]++; return nil })
+
+	f1()
+	f2()
+	done := make(chan error)
+	go func() { done <- f1() }()
+	c.BlockUntil(1)
+	if cnt != [2]int{1, 1} {
+		t.Fatalf("unexpected result: %v", cnt)
+	}
+	c.Advance(time.Second)
+	<-done
+	if cnt != [2]int{2, 1} {
+		t.Fatalf("unexpected result: %v", cnt)
+	}
+}
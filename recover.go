@@ -0,0 +1,103 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is an error converted from a recovered panic
+type PanicError struct {
+	// the value passed to panic()
+	Value any
+	// stack trace of the goroutine when panicking
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value passed to panic() if it is an error, so errors.Is and
+// errors.As work with panic(err)
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// recoverTo converts panic into *PanicError
+//
+// It checks completed instead of value of recover(), which is nil for panic(nil)
+// in older Go.
+func recoverTo(err *error, completed *bool) {
+	v := recover()
+	if *completed {
+		return
+	}
+	*err = &PanicError{Value: v, Stack: debug.Stack()}
+}
+
+// Recover creates a function which converts panic in f into *PanicError
+//
+// Helpers running f in background (InfiniteLoop, Retry, TilErrAsync ...) do not
+// recover panics, so a panicking f kills whole program. Wrap f with Recover to get
+// the panic through normal Err channel or return value instead.
+//
+//    ctrl := InfiniteLoop(Recover(task))
+//    err := <-ctrl.Err
+//    var pe *PanicError
+//    if errors.As(err, &pe) {
+//        log.Printf("task panicked: %v\n%s", pe.Value, pe.Stack)
+//    }
+//
+// Note that Retry treats *PanicError as ordinary error, so f is retried.
+func Recover(f func() error) func() error {
+	return func() (err error) {
+		completed := false
+		defer recoverTo(&err, &completed)
+		err = f()
+		completed = true
+		return
+	}
+}
+
+// RecoverCtx is identical to Recover, but for context-aware functions like those
+// passed to InfiniteLoopCtx
+func RecoverCtx(f func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) (err error) {
+		completed := false
+		defer recoverTo(&err, &completed)
+		err = f(ctx)
+		completed = true
+		return
+	}
+}
+
+// RecoverValue is identical to Recover, but f returns a value
+func RecoverValue[T any](f func() (T, error)) func() (T, error) {
+	return func() (ret T, err error) {
+		completed := false
+		defer recoverTo(&err, &completed)
+		ret, err = f()
+		completed = true
+		return
+	}
+}
+
+// RecoverAll wraps every f in funcs with Recover, for TilErrAsync and friends
+//
+//    err := TilErrAsync(RecoverAll(initConn1, initConn2, initConn3)...)
+func RecoverAll(funcs ...func() error) (ret []func() error) {
+	ret = make([]func() error, len(funcs))
+	for idx, f := range funcs {
+		ret[idx] = Recover(f)
+	}
+
+	return
+}
@@ -0,0 +1,28 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"errors"
+	"fmt"
+)
+
+func ExampleRecover() {
+	task := func() error {
+		var m map[string]int
+		m["a"] = 1 // panics
+		return nil
+	}
+
+	ctrl := InfiniteLoop(Recover(task))
+	err := <-ctrl.Err
+
+	var pe *PanicError
+	if errors.As(err, &pe) {
+		fmt.Println("task panicked:", pe.Value)
+	}
+
+	// output: task panicked: assignment to entry in nil map
+}
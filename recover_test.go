@@ -0,0 +1,90 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	theErr := errors.New("the error")
+	cases := []struct {
+		name  string
+		value any
+		is    error
+	}{
+		{name: "string", value: "oops"},
+		{name: "error", value: theErr, is: theErr},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := Recover(func() error { panic(c.value) })()
+
+			var pe *PanicError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expected *PanicError, got %T", err)
+			}
+			if pe.Value != c.value {
+				t.Errorf("unexpected value: %v", pe.Value)
+			}
+			if !strings.Contains(string(pe.Stack), "TestRecover") {
+				t.Errorf("unexpected stack: %s", pe.Stack)
+			}
+			if c.is != nil && !errors.Is(err, c.is) {
+				t.Error("expected to unwrap to ", c.is)
+			}
+		})
+	}
+}
+
+func TestRecoverPassThrough(t *testing.T) {
+	theErr := errors.New("the error")
+	if err := Recover(func() error { return theErr })(); err != theErr {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	v, err := RecoverValue(func() (int, error) { return 1, nil })()
+	if err != nil || v != 1 {
+		t.Fatalf("unexpected result: %d, %v", v, err)
+	}
+}
+
+func TestRecoverInHelpers(t *testing.T) {
+	var pe *PanicError
+	boom := func() error { panic("boom") }
+
+	ctrl := InfiniteLoopCtx(context.Background(), RecoverCtx(func(_ context.Context) error {
+		return boom()
+	}))
+	if err := <-ctrl.Err; !errors.As(err, &pe) {
+		t.Errorf("InfiniteLoop: expected *PanicError, got %v", err)
+	}
+
+	if err := TryAtMost(2, Recover(boom)); !errors.As(err, &pe) {
+		t.Errorf("TryAtMost: expected *PanicError, got %v", err)
+	}
+
+	err := TilErrAsync(RecoverAll(func() error { return nil }, boom)...)
+	if !errors.As(err, &pe) {
+		t.Errorf("TilErrAsync: expected *PanicError, got %v", err)
+	}
+}
+
+func TestRecoverPanicNil(t *testing.T) {
+	err := Recover(func() error { panic(nil) })()
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected *PanicError, got %v", err)
+	}
+
+	_, err = RecoverValue(func() (int, error) { panic(nil) })()
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected *PanicError, got %v", err)
+	}
+}
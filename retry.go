@@ -27,7 +27,7 @@ func Recorded(f func(idx uint64) error) (ret func() error) {
 
 // Retry runs f() until it returns nil
 //
-// err is closed when f() returns nil
+// err is closed when f() returns nil, or after a PermanentError is sent to it
 //
 // common usacase:
 //
@@ -39,6 +39,8 @@ func Recorded(f func(idx uint64) error) (ret func() error) {
 //    }
 //    log.Print("#%d attempt is successfully done", idx)
 //
+// See RetryOpts if you want to log them with a *slog.Logger.
+//
 // WARNING: err is not buffered, so it won't execute before error in err is consumed
 func Retry(f func() error) (err chan error) {
 	err = make(chan error)
@@ -52,6 +54,9 @@ func Retry(f func() error) (err chan error) {
 			}
 
 			err <- e
+			if IsPermanent(e) {
+				return
+			}
 		}
 	}(err)
 
@@ -72,20 +77,48 @@ func TriesAtMost(n uint64, f func() error) (err chan error) {
 	}))
 }
 
-// TryAtMost wraps TriesAtMost, returns last error iff all attempts failed.
+// TryAtMost wraps TriesAtMost, returns last error iff all attempts failed, or
+// the PermanentError which stops retrying.
 func TryAtMost(n uint64, f func() error) (err error) {
 	ch := TriesAtMost(n, f)
 	cnt := uint64(0)
 	for err = range ch {
 		cnt++
 	}
-	if cnt < n {
+	if cnt < n && !IsPermanent(err) {
 		err = nil
 	}
 
 	return
 }
 
+// RetryOpts is identical to Retry, but accepts options
+//
+// Every call to f is reported as an attempt. Errors are still sent to err, use
+// IgnoreErr if they are consumed by the Observer (like a logger) already.
+//
+// Supported options: WithClock, WithObserver, WithName, WithClassifier,
+// WithMaxElapsed, WithDeadline, WithContext
+func RetryOpts(f func() error, opts ...Option) (err chan error) {
+	return Retry(newOptions(opts).retried(f))
+}
+
+// TriesAtMostOpts is identical to TriesAtMost, but accepts options
+//
+// Supported options: WithClock, WithObserver, WithName, WithClassifier,
+// WithMaxElapsed, WithDeadline, WithContext
+func TriesAtMostOpts(n uint64, f func() error, opts ...Option) (err chan error) {
+	return TriesAtMost(n, newOptions(opts).retried(f))
+}
+
+// TryAtMostOpts is identical to TryAtMost, but accepts options
+//
+// Supported options: WithClock, WithObserver, WithName, WithClassifier,
+// WithMaxElapsed, WithDeadline, WithContext
+func TryAtMostOpts(n uint64, f func() error, opts ...Option) (err error) {
+	return TryAtMost(n, newOptions(opts).retried(f))
+}
+
 // IgnoreErr drops all errors in ch asynchronously
 //
 // If you need it synchronously, just use "for range ch {}".
@@ -0,0 +1,117 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routinestest
+
+import (
+	"sync"
+	"time"
+)
+
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// Clock is a fake clock which is advanced manually, it implements routines.Clock
+//
+// Time never goes by unless Advance() or Set() is called, so Sleep() blocks until
+// the clock is advanced far enough. A common pattern is
+//
+//    c := NewClock(time.Now())
+//    f := routines.RunAtLeastOpts(time.Second, task, routines.WithClock(c))
+//    go f()
+//    c.BlockUntil(1)     // wait for f to sleep
+//    c.Advance(time.Second) // wake it up
+type Clock struct {
+	lock    sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+// NewClock creates a fake clock starts at now
+func NewClock(now time.Time) (ret *Clock) {
+	ret = &Clock{now: now}
+	ret.cond = sync.NewCond(&ret.lock)
+	return
+}
+
+// Now returns current time of the fake clock
+func (c *Clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// After sends current fake time to returned channel when the clock is advanced
+// by at least d
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	c.waiters = append(c.waiters, &waiter{at: c.now.Add(d), ch: ch})
+	c.cond.Broadcast()
+	return ch
+}
+
+// Sleep blocks until the clock is advanced by at least d
+func (c *Clock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Advance moves the clock forward by d and wakes up waiters accordingly
+func (c *Clock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.set(c.now.Add(d))
+}
+
+// Set moves the clock to t and wakes up waiters accordingly
+//
+// Moving clock backward is allowed, waiters are not affected in that case.
+func (c *Clock) Set(t time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.set(t)
+}
+
+func (c *Clock) set(t time.Time) {
+	c.now = t
+	left := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(t) {
+			left = append(left, w)
+			continue
+		}
+		w.ch <- t
+	}
+	for idx := len(left); idx < len(c.waiters); idx++ {
+		c.waiters[idx] = nil
+	}
+	c.waiters = left
+	c.cond.Broadcast()
+}
+
+// Waiters returns number of pending Sleep() and After() calls
+func (c *Clock) Waiters() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil blocks until there are at least n pending Sleep() or After() calls
+func (c *Clock) BlockUntil(n int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
@@ -0,0 +1,61 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routinestest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockSleep(t *testing.T) {
+	begin := time.Unix(0, 0)
+	c := NewClock(begin)
+	done := make(chan time.Time)
+	go func() {
+		c.Sleep(time.Second)
+		done <- c.Now()
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(999 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("Sleep() returns before the clock is advanced enough")
+	default:
+	}
+
+	c.Advance(time.Millisecond)
+	if now := <-done; !now.Equal(begin.Add(time.Second)) {
+		t.Fatalf("unexpected time: %v", now)
+	}
+	if w := c.Waiters(); w != 0 {
+		t.Fatalf("expected no waiter, got %d", w)
+	}
+}
+
+func TestClockAfter(t *testing.T) {
+	begin := time.Unix(0, 0)
+	c := NewClock(begin)
+
+	if now := <-c.After(0); !now.Equal(begin) {
+		t.Fatalf("unexpected time: %v", now)
+	}
+
+	ch1 := c.After(time.Second)
+	ch2 := c.After(2 * time.Second)
+	c.Set(begin.Add(1500 * time.Millisecond))
+
+	if now := <-ch1; !now.Equal(begin.Add(1500 * time.Millisecond)) {
+		t.Fatalf("unexpected time: %v", now)
+	}
+	select {
+	case <-ch2:
+		t.Fatal("second waiter should not be fired")
+	default:
+	}
+	if w := c.Waiters(); w != 1 {
+		t.Fatalf("expected 1 waiter, got %d", w)
+	}
+}
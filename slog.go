@@ -0,0 +1,120 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+//go:build go1.21
+
+package routines
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogObserver is an Observer (and LoopObserver) which writes events to a
+// *slog.Logger as structured attributes
+//
+// Attributes are "name" (if set by WithName), "attempt" (zero-based),
+// "duration", "delay" and "err". Change the levels to fit your needs before
+// passing it to helpers.
+//
+//    ob := NewSlogObserver(slog.Default())
+//    ob.FailedLevel = slog.LevelError
+//    IgnoreErr(RetryOpts(f, WithObserver(ob), WithName("fetch")))
+//
+// It requires Go 1.21 or later.
+type SlogObserver struct {
+	Logger *slog.Logger
+
+	StartedLevel   slog.Level // default to debug
+	SucceededLevel slog.Level // default to debug
+	FailedLevel    slog.Level // default to warn
+	WaitedLevel    slog.Level // default to debug
+	DroppedLevel   slog.Level // default to debug
+	LoopLevel      slog.Level // start and stop of loops, default to info
+}
+
+// NewSlogObserver creates a SlogObserver with default levels
+//
+// slog.Default() is used if l is nil.
+func NewSlogObserver(l *slog.Logger) *SlogObserver {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &SlogObserver{
+		Logger:         l,
+		StartedLevel:   slog.LevelDebug,
+		SucceededLevel: slog.LevelDebug,
+		FailedLevel:    slog.LevelWarn,
+		WaitedLevel:    slog.LevelDebug,
+		DroppedLevel:   slog.LevelDebug,
+		LoopLevel:      slog.LevelInfo,
+	}
+}
+
+// WithLogger is a shortcut of WithObserver(NewSlogObserver(l))
+//
+// It replaces the Observer set by WithObserver, and vice versa.
+func WithLogger(l *slog.Logger) Option {
+	return WithObserver(NewSlogObserver(l))
+}
+
+func (s *SlogObserver) log(lv slog.Level, msg string, e Event, attrs ...slog.Attr) {
+	if e.Name != "" {
+		attrs = append([]slog.Attr{slog.String("name", e.Name)}, attrs...)
+	}
+	s.Logger.LogAttrs(context.Background(), lv, msg, attrs...)
+}
+
+// Started implements Observer
+func (s *SlogObserver) Started(e Event) {
+	s.log(s.StartedLevel, "routines: attempt started", e,
+		slog.Uint64("attempt", e.Attempt),
+	)
+}
+
+// Finished implements Observer
+func (s *SlogObserver) Finished(e Event) {
+	if e.Err == nil {
+		s.log(s.SucceededLevel, "routines: attempt succeeded", e,
+			slog.Uint64("attempt", e.Attempt),
+			slog.Duration("duration", e.Duration),
+		)
+		return
+	}
+
+	s.log(s.FailedLevel, "routines: attempt failed", e,
+		slog.Uint64("attempt", e.Attempt),
+		slog.Duration("duration", e.Duration),
+		slog.Any("err", e.Err),
+	)
+}
+
+// Waited implements Observer
+func (s *SlogObserver) Waited(e Event) {
+	s.log(s.WaitedLevel, "routines: attempt delayed", e,
+		slog.Uint64("attempt", e.Attempt),
+		slog.Duration("delay", e.Duration),
+	)
+}
+
+// Dropped implements Observer
+func (s *SlogObserver) Dropped(e Event) {
+	s.log(s.DroppedLevel, "routines: call dropped", e,
+		slog.Uint64("attempt", e.Attempt),
+	)
+}
+
+// LoopStarted implements LoopObserver
+func (s *SlogObserver) LoopStarted(e Event) {
+	s.log(s.LoopLevel, "routines: loop started", e)
+}
+
+// LoopStopped implements LoopObserver
+func (s *SlogObserver) LoopStopped(e Event) {
+	s.log(s.LoopLevel, "routines: loop stopped", e,
+		slog.Uint64("iterations", e.Attempt),
+		slog.Duration("duration", e.Duration),
+		slog.Any("err", e.Err),
+	)
+}
@@ -0,0 +1,34 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+//go:build go1.21
+
+package routines
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+)
+
+func ExampleWithLogger() {
+	l := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			// remove time and duration for stable output
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	err := TryAtMostOpts(2, func() error {
+		return errors.New("oops")
+	}, WithLogger(l), WithName("fetch"))
+	l.Info("gave up", "err", err)
+
+	// output: level=WARN msg="routines: attempt failed" name=fetch attempt=0 err=oops
+	// level=WARN msg="routines: attempt failed" name=fetch attempt=1 err=oops
+	// level=INFO msg="gave up" err=oops
+}
@@ -0,0 +1,112 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+//go:build go1.21
+
+package routines
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+var _ LoopObserver = (*SlogObserver)(nil)
+
+func parseLogs(t *testing.T, buf *bytes.Buffer) (ret []map[string]any) {
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var v map[string]any
+		if err := dec.Decode(&v); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		ret = append(ret, v)
+	}
+	return
+}
+
+func TestSlogObserverBackoff(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	buf := &bytes.Buffer{}
+	l := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	cnt := 0
+	done := make(chan error)
+	go func() {
+		done <- TryAtMostWithBackoff(2, ConstantBackoff(time.Second), func() error {
+			if cnt++; cnt == 1 {
+				return errors.New("oops")
+			}
+			return nil
+		}, WithClock(c), WithLogger(l), WithName("fetch"))
+	}()
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	logs := parseLogs(t, buf)
+	expect := []struct{ level, msg string }{
+		{"DEBUG", "routines: attempt started"},
+		{"WARN", "routines: attempt failed"},
+		{"DEBUG", "routines: attempt delayed"},
+		{"DEBUG", "routines: attempt started"},
+		{"DEBUG", "routines: attempt succeeded"},
+	}
+	if len(logs) != len(expect) {
+		t.Fatalf("unexpected logs: %v", logs)
+	}
+	for idx, e := range expect {
+		if logs[idx]["level"] != e.level || logs[idx]["msg"] != e.msg {
+			t.Errorf("#%d: unexpected log: %v", idx, logs[idx])
+		}
+		if logs[idx]["name"] != "fetch" {
+			t.Errorf("#%d: unexpected name: %v", idx, logs[idx]["name"])
+		}
+	}
+	if logs[1]["err"] != "oops" || logs[1]["attempt"] != float64(0) {
+		t.Errorf("unexpected failed log: %v", logs[1])
+	}
+	if logs[2]["delay"] != float64(time.Second) || logs[2]["attempt"] != float64(1) {
+		t.Errorf("unexpected delayed log: %v", logs[2])
+	}
+}
+
+func TestSlogObserverLoop(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := slog.New(slog.NewJSONHandler(buf, nil))
+	theErr := errors.New("stop")
+
+	cnt := 0
+	ctrl := InfiniteLoopOpts(func() error {
+		if cnt++; cnt == 3 {
+			return theErr
+		}
+		return nil
+	}, WithLogger(l))
+	if err := <-ctrl.Err; err != theErr {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	// attempts are logged at debug level, which is filtered
+	logs := parseLogs(t, buf)
+	if len(logs) != 3 {
+		t.Fatalf("unexpected logs: %v", logs)
+	}
+	if logs[0]["msg"] != "routines: loop started" {
+		t.Errorf("unexpected log: %v", logs[0])
+	}
+	if logs[1]["msg"] != "routines: attempt failed" {
+		t.Errorf("unexpected log: %v", logs[1])
+	}
+	if l := logs[2]; l["msg"] != "routines: loop stopped" || l["iterations"] != float64(3) || l["err"] != "stop" {
+		t.Errorf("unexpected log: %v", l)
+	}
+}
@@ -5,8 +5,10 @@
 package routines
 
 import (
+	"context"
 	"errors"
 	"sync"
+	"time"
 )
 
 var ErrRunning = errors.New("StatefulFunc: function is running")
@@ -19,15 +21,67 @@ type StatefulFunc interface {
 	// run this function, blocks until ran
 	Run() (err error)
 	// blocks until it's free to run, and hold the lock to prevent others running
-	// calling Run() without release the lock cause deadlock! use with care.
+	// calling Run() without release the lock cause deadlock! use with care, or
+	// use RunContext() and LockContext() of StatefulFuncExt (created by
+	// NewStatefulFuncOpts) which can give up.
 	//
 	// It's safe to call release multiple times, only first time is executed.
 	Lock() (release func())
 }
 
+// StatefulFuncExt is a StatefulFunc with more ways to run it and execution
+// statistics
+//
+// Use NewStatefulFuncOpts to create one.
+type StatefulFuncExt interface {
+	StatefulFunc
+	// like Run, but callers arriving while it is running (or about to run by
+	// another Join) wait for that execution and share its result, instead of
+	// running it again. They are reported as Dropped to the Observer.
+	Join() (err error)
+	// like Lock, but gives up and returns ctx.Err() if ctx is done first
+	LockContext(ctx context.Context) (release func(), err error)
+	// like Lock, but gives up and returns context.DeadlineExceeded after timeout
+	TryLockFor(timeout time.Duration) (release func(), err error)
+	// like Run, but gives up waiting and returns ctx.Err() if ctx is done first
+	//
+	// ctx is not passed to the function, so it cannot interrupt running one.
+	RunContext(ctx context.Context) (err error)
+	// returns a snapshot of execution statistics
+	Stats() FuncStats
+}
+
+// FuncStats is execution statistics of a StatefulFunc
+type FuncStats struct {
+	// Start and finish time of last execution, zero if never
+	LastStarted  time.Time
+	LastFinished time.Time
+	// Result of last finished execution
+	LastErr error
+
+	// number of finished executions
+	Runs uint64
+	// number of finished executions which return non-nil error
+	Failures uint64
+	// number of TryRun() calls which return ErrRunning
+	Rejected uint64
+	// number of successful Lock(), LockContext() and TryLockFor() calls
+	Locks uint64
+
+	// Mean and max execution time of finished executions
+	MeanDuration time.Duration
+	MaxDuration  time.Duration
+}
+
 type statefulFunc struct {
 	token chan *struct{}
 	f     func() error
+	o     *options
+
+	lock  sync.Mutex
+	cur   *sharedCall // execution which callers of Join() can share
+	stats FuncStats
+	total time.Duration // for stats.MeanDuration
 }
 
 func (f *statefulFunc) IsRunning() (yes bool) {
@@ -43,9 +97,12 @@ func (f *statefulFunc) IsRunning() (yes bool) {
 func (f *statefulFunc) TryRun() (err error) {
 	select {
 	case x := <-f.token:
-		err = f.f()
+		err = f.exec()
 		f.token <- x
 	default:
+		f.lock.Lock()
+		f.stats.Rejected++
+		f.lock.Unlock()
 		err = ErrRunning
 	}
 
@@ -54,13 +111,144 @@ func (f *statefulFunc) TryRun() (err error) {
 
 func (f *statefulFunc) Run() (err error) {
 	x := <-f.token
-	err = f.f()
+	err = f.exec()
 	f.token <- x
 	return
 }
 
-func (f *statefulFunc) Lock() (release func()) {
+// exec runs f with token held, and shares it to Join() if possible
+func (f *statefulFunc) exec() (err error) {
+	f.lock.Lock()
+	c := f.cur
+	mine := c == nil
+	if mine {
+		c = newSharedCall()
+		f.cur = c
+	}
+	f.lock.Unlock()
+
+	err = f.call()
+	if mine {
+		f.done(c, err)
+	}
+	return
+}
+
+// call runs f with token held, and updates stats
+func (f *statefulFunc) call() (err error) {
+	begin := f.o.clock.Now()
+	f.lock.Lock()
+	f.stats.LastStarted = begin
+	f.lock.Unlock()
+
+	err = f.o.observe(f.o.next(), f.f)
+
+	end := f.o.clock.Now()
+	d := end.Sub(begin)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.stats.LastFinished = end
+	f.stats.LastErr = err
+	f.stats.Runs++
+	if err != nil {
+		f.stats.Failures++
+	}
+	f.total += d
+	f.stats.MeanDuration = f.total / time.Duration(f.stats.Runs)
+	if d > f.stats.MaxDuration {
+		f.stats.MaxDuration = d
+	}
+	return
+}
+
+func (f *statefulFunc) Stats() FuncStats {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.stats
+}
+
+func (f *statefulFunc) done(c *sharedCall, err error) {
+	f.lock.Lock()
+	f.cur = nil
+	f.lock.Unlock()
+	c.finish(err)
+}
+
+func (f *statefulFunc) Join() (err error) {
+	f.lock.Lock()
+	if c := f.cur; c != nil {
+		f.lock.Unlock()
+		f.o.dropped(f.o.next())
+		return c.wait()
+	}
+	c := newSharedCall()
+	f.cur = c
+	f.lock.Unlock()
+
 	x := <-f.token
+	err = f.call()
+	f.done(c, err)
+	f.token <- x
+	return
+}
+
+func (f *statefulFunc) RunContext(ctx context.Context) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	select {
+	case x := <-f.token:
+		err = f.exec()
+		f.token <- x
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return
+}
+
+func (f *statefulFunc) Lock() (release func()) {
+	return f.locked(<-f.token)
+}
+
+func (f *statefulFunc) LockContext(ctx context.Context) (release func(), err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	select {
+	case x := <-f.token:
+		release = f.locked(x)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return
+}
+
+func (f *statefulFunc) TryLockFor(timeout time.Duration) (release func(), err error) {
+	select {
+	case x := <-f.token:
+		return f.locked(x), nil
+	default:
+	}
+	if timeout <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+
+	timer, stop := newTimer(f.o.clock, timeout)
+	defer stop()
+	select {
+	case x := <-f.token:
+		release = f.locked(x)
+	case <-timer:
+		err = context.DeadlineExceeded
+	}
+	return
+}
+
+// locked updates stats and creates release function of the token x
+func (f *statefulFunc) locked(x *struct{}) (release func()) {
+	f.lock.Lock()
+	f.stats.Locks++
+	f.lock.Unlock()
 	once := &sync.Once{}
 
 	return func() {
@@ -71,10 +259,22 @@ func (f *statefulFunc) Lock() (release func()) {
 }
 
 // NewStatefulFunc creates a new StatefulFunc
+//
+// Use NewStatefulFuncOpts if you need Join, LockContext, TryLockFor, RunContext
+// or Stats.
 func NewStatefulFunc(f func() error) (ret StatefulFunc) {
+	return NewStatefulFuncOpts(f)
+}
+
+// NewStatefulFuncOpts is identical to NewStatefulFunc, but accepts options and
+// returns StatefulFuncExt
+//
+// Supported options: WithClock, WithObserver, WithName
+func NewStatefulFuncOpts(f func() error, opts ...Option) (ret StatefulFuncExt) {
 	x := &statefulFunc{
 		token: make(chan *struct{}, 1),
 		f:     f,
+		o:     newOptions(opts),
 	}
 	x.token <- nil
 
@@ -6,6 +6,7 @@ package routines
 
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -84,3 +85,28 @@ func ExampleStatefulFunc_lock() {
 	// run
 	// run
 }
+
+func ExampleStatefulFuncExt_join() {
+	cnt := 0
+	f := func() error {
+		time.Sleep(10 * time.Millisecond)
+		cnt++
+		fmt.Println("refresh cache")
+		return nil
+	}
+
+	sf := NewStatefulFuncOpts(f)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sf.Join() // all callers share same execution
+		}()
+	}
+	wg.Wait()
+	fmt.Println(cnt)
+
+	// output: refresh cache
+	// 1
+}
@@ -0,0 +1,208 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+func TestStatefulFuncStats(t *testing.T) {
+	begin := time.Now()
+	c := routinestest.NewClock(begin)
+	theErr := errors.New("")
+	var (
+		d   time.Duration
+		err error
+	)
+	sf := NewStatefulFuncOpts(func() error {
+		c.Advance(d)
+		return err
+	}, WithClock(c))
+
+	if s := sf.Stats(); s != (FuncStats{}) {
+		t.Fatalf("unexpected initial stats: %+v", s)
+	}
+
+	d = time.Second
+	sf.Run()
+	d, err = 3*time.Second, theErr
+	sf.TryRun()
+	d, err = 2*time.Second, nil
+	sf.Join()
+
+	release := sf.Lock()
+	if e := sf.TryRun(); e != ErrRunning {
+		t.Fatal("unexpected error: ", e)
+	}
+	release()
+
+	s := sf.Stats()
+	if s.Runs != 3 || s.Failures != 1 || s.Rejected != 1 || s.Locks != 1 {
+		t.Fatalf("unexpected counters: %+v", s)
+	}
+	if s.MeanDuration != 2*time.Second || s.MaxDuration != 3*time.Second {
+		t.Fatalf("unexpected durations: %v, %v", s.MeanDuration, s.MaxDuration)
+	}
+	if !s.LastStarted.Equal(begin.Add(4*time.Second)) || !s.LastFinished.Equal(begin.Add(6*time.Second)) {
+		t.Fatalf("unexpected time: %v, %v", s.LastStarted, s.LastFinished)
+	}
+	if s.LastErr != nil {
+		t.Fatal("unexpected last error: ", s.LastErr)
+	}
+}
+
+func TestStatefulFuncContext(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	cnt := 0
+	sf := NewStatefulFuncOpts(func() error {
+		cnt++
+		return nil
+	}, WithClock(c))
+
+	release := sf.Lock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- sf.RunContext(ctx) }()
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatal("unexpected error: ", err)
+	}
+	if _, err := sf.LockContext(ctx); err != context.Canceled {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	if _, err := sf.TryLockFor(0); err != context.DeadlineExceeded {
+		t.Fatal("unexpected error: ", err)
+	}
+	go func() {
+		_, err := sf.TryLockFor(time.Second)
+		done <- err
+	}()
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	if err := <-done; err != context.DeadlineExceeded {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	// token is not leaked
+	release()
+	if err := sf.RunContext(context.Background()); err != nil || cnt != 1 {
+		t.Fatalf("unexpected result: %d, %v", cnt, err)
+	}
+	r, err := sf.TryLockFor(time.Second)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	r()
+	r, err = sf.LockContext(context.Background())
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	r()
+	if s := sf.Stats(); s.Locks != 3 {
+		t.Fatal("unexpected lock count: ", s.Locks)
+	}
+}
+
+func TestStatefulFuncJoin(t *testing.T) {
+	merged := newDropSignal()
+	started := make(chan struct{})
+	release := make(chan error)
+	cnt := 0
+	sf := NewStatefulFuncOpts(func() error {
+		cnt++
+		started <- struct{}{}
+		return <-release
+	}, WithObserver(merged))
+
+	theErr := errors.New("")
+	results := make(chan error)
+	join := func() { results <- sf.Join() }
+
+	// all joiners share execution of first Join()
+	go join()
+	<-started
+	for x := 0; x < 5; x++ {
+		go join()
+		<-merged.ch
+	}
+	release <- theErr
+	for x := 0; x < 6; x++ {
+		if err := <-results; err != theErr {
+			t.Fatal("unexpected error: ", err)
+		}
+	}
+	if cnt != 1 {
+		t.Fatalf("expected run once, got %d", cnt)
+	}
+
+	// Join() shares execution of Run() and TryRun()
+	runs := map[string]func() error{
+		"Run":    sf.Run,
+		"TryRun": sf.TryRun,
+	}
+	for name, run := range runs {
+		run := run
+		cnt = 0
+		go func() { results <- run() }()
+		<-started
+		go join()
+		<-merged.ch
+		release <- theErr
+		for x := 0; x < 2; x++ {
+			if err := <-results; err != theErr {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+		}
+		if cnt != 1 {
+			t.Fatalf("%s: expected run once, got %d", name, cnt)
+		}
+	}
+}
+
+func TestStatefulFuncJoinLocked(t *testing.T) {
+	merged := newDropSignal()
+	started := make(chan struct{})
+	release := make(chan error)
+	sf := NewStatefulFuncOpts(func() error {
+		started <- struct{}{}
+		return <-release
+	}, WithObserver(merged))
+
+	unlock := sf.Lock()
+	runResult := make(chan error)
+	joinResults := make(chan error)
+	go func() { runResult <- sf.Run() }()
+	go func() { joinResults <- sf.Join() }()
+	go func() { joinResults <- sf.Join() }()
+
+	// one Join() waits for the lock, another one joins it
+	<-merged.ch
+	unlock()
+
+	// Run() and Join() are executed one by one, in any order
+	errs := []error{errors.New("1"), errors.New("2")}
+	for _, err := range errs {
+		<-started
+		release <- err
+	}
+
+	j1, j2, r := <-joinResults, <-joinResults, <-runResult
+	if j1 != j2 {
+		t.Fatalf("expected joiners share same result, got %v and %v", j1, j2)
+	}
+	if r == j1 || (r != errs[0] && r != errs[1]) || (j1 != errs[0] && j1 != errs[1]) {
+		t.Fatalf("unexpected results: run=%v, join=%v", r, j1)
+	}
+	if s := sf.Stats(); s.Runs != 2 {
+		t.Fatalf("expected run twice, got %d", s.Runs)
+	}
+}
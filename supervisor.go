@@ -0,0 +1,244 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrStop can be returned by supervised task to stop its loop normally. It is
+	// not restarted unless the policy is RestartAlways.
+	ErrStop = errors.New("Supervisor: task stopped")
+	// ErrTooManyRestarts is reported when restart intensity is exceeded.
+	ErrTooManyRestarts = errors.New("Supervisor: too many restarts")
+)
+
+// RestartPolicy determines if a stopped loop should be restarted
+type RestartPolicy int
+
+const (
+	// restarts the loop unless it is stopped by ErrStop
+	RestartOnFailure RestartPolicy = iota
+	// restarts the loop no matter why it is stopped
+	RestartAlways
+	// never restarts the loop
+	RestartNever
+)
+
+// SupervisorStrategy determines which loops are restarted
+type SupervisorStrategy int
+
+const (
+	// restarts only the stopped loop
+	OneForOne SupervisorStrategy = iota
+	// stops all other loops, and restarts all of them
+	OneForAll
+)
+
+// SupervisorConfig configures a Supervisor
+type SupervisorConfig struct {
+	Policy   RestartPolicy
+	Strategy SupervisorStrategy
+	// Supervisor gives up if more than MaxRestarts restarts occur within
+	// Window. 0 means no limit, and Window <= 0 means forever.
+	MaxRestarts int
+	Window      time.Duration
+	// Waits before restarting. idx passed to it is the number of restarts
+	// within Window. nil means restarting immediately.
+	Backoff Backoff
+}
+
+// Supervisor runs tasks in InfiniteLoopCtx, and restarts stopped loops
+//
+// It acts like Erlang supervisors, see SupervisorConfig for detail.
+type Supervisor struct {
+	cfg SupervisorConfig
+	o   *options
+}
+
+// NewSupervisor creates a Supervisor
+//
+// Supported options: WithClock, WithObserver, WithName. Started and Finished are
+// reported when a loop starts and stops, and Waited is reported for Backoff.
+func NewSupervisor(cfg SupervisorConfig, opts ...Option) (ret *Supervisor) {
+	return &Supervisor{
+		cfg: cfg,
+		o:   newOptions(opts),
+	}
+}
+
+type supervisedExit struct {
+	idx     int
+	attempt uint64
+	begin   time.Time
+	err     error
+}
+
+// Run runs every task with InfiniteLoopCtx under supervision
+//
+// Error of every stopped loop is sent to Err, even if it is restarted later. Err is
+// closed when
+//
+//   - Cancel() is called: all loops are cancelled, and context.Canceled is sent
+//   - restart intensity is exceeded: all loops are cancelled, and an error
+//     wrapping both ErrTooManyRestarts and last error is sent
+//   - all loops are stopped and none of them should be restarted
+//
+// Like Retry, Err is not buffered, so no loop is restarted before the error is
+// consumed.
+//
+//    sup := NewSupervisor(SupervisorConfig{
+//        MaxRestarts: 5,
+//        Window:      time.Minute,
+//        Backoff:     ExponentialBackoff(time.Second, time.Minute),
+//    })
+//    ctrl := sup.Run(readMessages, sendPing)
+//    defer ctrl.Cancel()
+//    for err := range ctrl.Err {
+//        log.Print("loop stopped: ", err)
+//    }
+func (s *Supervisor) Run(tasks ...func(ctx context.Context) error) (ret InfiniteLoopControl) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ret = InfiniteLoopControl{
+		Cancel: cancel,
+		Err:    make(chan error),
+	}
+
+	go s.supervise(ctx, ret.Err, tasks)
+	return
+}
+
+func (s *Supervisor) shouldRestart(err error) bool {
+	switch s.cfg.Policy {
+	case RestartAlways:
+		return true
+	case RestartNever:
+		return false
+	}
+	return !errors.Is(err, ErrStop)
+}
+
+func (s *Supervisor) supervise(ctx context.Context, errch chan error, tasks []func(context.Context) error) {
+	defer close(errch)
+
+	exits := make(chan supervisedExit, len(tasks))
+	ctrls := make([]InfiniteLoopControl, len(tasks))
+	running := 0
+	start := func(idx int) {
+		e := supervisedExit{idx: idx, attempt: s.o.next(), begin: s.o.clock.Now()}
+		s.o.observer.Started(Event{Name: s.o.name, Attempt: e.attempt})
+		ctrls[idx] = InfiniteLoopCtx(ctx, tasks[idx])
+		running++
+		go func(e supervisedExit, ch chan error) {
+			e.err = <-ch
+			s.o.observer.Finished(Event{
+				Name:     s.o.name,
+				Attempt:  e.attempt,
+				Duration: s.o.clock.Now().Sub(e.begin),
+				Err:      e.err,
+			})
+			exits <- e
+		}(e, ctrls[idx].Err)
+	}
+	stopAll := func() {
+		for _, c := range ctrls {
+			c.Cancel()
+		}
+		for ; running > 0; running-- {
+			<-exits
+		}
+	}
+
+	for idx := range tasks {
+		start(idx)
+	}
+
+	var (
+		final  error
+		recent []time.Time
+		total  uint64
+		prev   time.Duration
+	)
+	done := ctx.Done()
+	for running > 0 {
+		var e supervisedExit
+		select {
+		case <-done:
+			stopAll()
+			final = ctx.Err()
+			continue
+		case e = <-exits:
+			running--
+		}
+		if ctx.Err() != nil {
+			// stopped by Cancel(), not a failure
+			stopAll()
+			final = ctx.Err()
+			continue
+		}
+
+		select {
+		case <-done:
+			stopAll()
+			final = ctx.Err()
+			continue
+		case errch <- e.err:
+		}
+		if !s.shouldRestart(e.err) {
+			continue
+		}
+
+		now := s.o.clock.Now()
+		cnt := total
+		if w := s.cfg.Window; w > 0 {
+			for len(recent) > 0 && now.Sub(recent[0]) >= w {
+				recent = recent[1:]
+			}
+			cnt = uint64(len(recent))
+		}
+		if n := s.cfg.MaxRestarts; n > 0 && cnt >= uint64(n) {
+			stopAll()
+			final = fmt.Errorf("%w: %w", ErrTooManyRestarts, e.err)
+			break
+		}
+
+		if s.cfg.Strategy == OneForAll {
+			stopAll()
+		}
+		if s.cfg.Backoff != nil {
+			prev = s.cfg.Backoff.Next(cnt, prev)
+			timer, stop := newTimer(s.o.clock, prev)
+			select {
+			case <-done:
+				stop()
+				stopAll()
+				final = ctx.Err()
+				continue
+			case <-timer:
+				s.o.waited(e.attempt, prev)
+			}
+		}
+		if s.cfg.Window > 0 {
+			recent = append(recent, now)
+		}
+		total++
+
+		if s.cfg.Strategy == OneForAll {
+			for idx := range tasks {
+				start(idx)
+			}
+			continue
+		}
+		start(e.idx)
+	}
+
+	if final != nil {
+		errch <- final
+	}
+}
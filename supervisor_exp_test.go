@@ -0,0 +1,33 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+func ExampleSupervisor() {
+	// connection is lost twice, and the reader stops normally after third connection
+	conns := 0
+	reader := func(ctx context.Context) error {
+		conns++
+		if conns < 3 {
+			return errors.New("connection lost")
+		}
+		return ErrStop
+	}
+
+	ctrl := NewSupervisor(SupervisorConfig{MaxRestarts: 5}).Run(reader)
+	defer ctrl.Cancel()
+	for err := range ctrl.Err {
+		fmt.Println(err)
+	}
+
+	// output: connection lost
+	// connection lost
+	// Supervisor: task stopped
+}
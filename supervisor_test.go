@@ -0,0 +1,183 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+// failN creates a task fails n times, then blocks until cancelled
+func failN(n int32, err error) (task func(context.Context) error, starts *int32) {
+	starts = new(int32)
+	return func(ctx context.Context) error {
+		if atomic.AddInt32(starts, 1) <= n {
+			return err
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}, starts
+}
+
+// waitStarts waits until task is started n times
+func waitStarts(starts *int32, n int32) {
+	for atomic.LoadInt32(starts) < n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSupervisorOneForOne(t *testing.T) {
+	theErr := errors.New("the error")
+	task1, starts1 := failN(2, theErr)
+	task2, starts2 := failN(0, theErr)
+
+	ctrl := NewSupervisor(SupervisorConfig{}).Run(task1, task2)
+	for x := 0; x < 2; x++ {
+		if err := <-ctrl.Err; err != theErr {
+			t.Fatal("unexpected error: ", err)
+		}
+	}
+	waitStarts(starts1, 3)
+
+	ctrl.Cancel()
+	if err := <-ctrl.Err; err != context.Canceled {
+		t.Fatal("unexpected error: ", err)
+	}
+	for err := range ctrl.Err {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	if x := atomic.LoadInt32(starts1); x != 3 {
+		t.Errorf("expected task1 to start 3 times, got %d", x)
+	}
+	if x := atomic.LoadInt32(starts2); x > 1 {
+		t.Errorf("expected task2 not restarted, got %d starts", x)
+	}
+}
+
+func TestSupervisorOneForAll(t *testing.T) {
+	theErr := errors.New("the error")
+	started := make(chan struct{}, 10)
+	var starts1 int32
+	task1 := func(ctx context.Context) error {
+		if atomic.AddInt32(&starts1, 1) == 1 {
+			// fails after task2 is started
+			<-started
+			return theErr
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	task2 := func(ctx context.Context) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ctrl := NewSupervisor(SupervisorConfig{
+		Strategy: OneForAll,
+	}).Run(task1, task2)
+	if err := <-ctrl.Err; err != theErr {
+		t.Fatal("unexpected error: ", err)
+	}
+	// task2 is restarted
+	<-started
+	waitStarts(&starts1, 2)
+
+	ctrl.Cancel()
+	if err := <-ctrl.Err; err != context.Canceled {
+		t.Fatal("unexpected error: ", err)
+	}
+	for err := range ctrl.Err {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	if x := atomic.LoadInt32(&starts1); x != 2 {
+		t.Errorf("expected task1 to start twice, got %d", x)
+	}
+}
+
+func TestSupervisorPolicy(t *testing.T) {
+	cases := []struct {
+		policy RestartPolicy
+		err    error
+		starts int32
+	}{
+		{policy: RestartOnFailure, err: ErrStop, starts: 1},
+		{policy: RestartAlways, err: ErrStop, starts: 2},
+		{policy: RestartNever, err: errors.New("the error"), starts: 1},
+	}
+
+	for _, c := range cases {
+		task, starts := failN(1, c.err)
+		ctrl := NewSupervisor(SupervisorConfig{Policy: c.policy}).Run(task)
+		if err := <-ctrl.Err; err != c.err {
+			t.Fatal("unexpected error: ", err)
+		}
+		if c.starts > 1 {
+			waitStarts(starts, c.starts)
+			ctrl.Cancel()
+			<-ctrl.Err
+		}
+		for err := range ctrl.Err {
+			t.Fatal("unexpected error: ", err)
+		}
+
+		if x := atomic.LoadInt32(starts); x != c.starts {
+			t.Errorf("policy %d: expected %d starts, got %d", c.policy, c.starts, x)
+		}
+	}
+}
+
+func TestSupervisorIntensity(t *testing.T) {
+	theErr := errors.New("the error")
+	task, starts := failN(100, theErr)
+
+	ctrl := NewSupervisor(SupervisorConfig{
+		MaxRestarts: 2,
+		Window:      time.Minute,
+	}).Run(task)
+	errs := []error{}
+	for err := range ctrl.Err {
+		errs = append(errs, err)
+	}
+
+	if l := len(errs); l != 4 {
+		t.Fatalf("expected 4 errors, got %d: %v", l, errs)
+	}
+	last := errs[3]
+	if !errors.Is(last, ErrTooManyRestarts) || !errors.Is(last, theErr) {
+		t.Fatal("unexpected error: ", last)
+	}
+	if x := atomic.LoadInt32(starts); x != 3 {
+		t.Errorf("expected 3 starts, got %d", x)
+	}
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	theErr := errors.New("the error")
+	task, starts := failN(1, theErr)
+
+	ctrl := NewSupervisor(SupervisorConfig{
+		Backoff: ConstantBackoff(time.Second),
+	}, WithClock(c)).Run(task)
+	defer ctrl.Cancel()
+	if err := <-ctrl.Err; err != theErr {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	c.BlockUntil(1)
+	if x := atomic.LoadInt32(starts); x != 1 {
+		t.Fatalf("expected not restarted yet, got %d starts", x)
+	}
+	c.Advance(time.Second)
+	waitStarts(starts, 2)
+}
@@ -42,3 +42,33 @@ func TilErrAsync(funcs ...func() error) (err error) {
 
 	return
 }
+
+// TilErrAsyncAll is identical to TilErrAsync, but returns all errors
+//
+// The returned error is a MultiError (or nil if all funcs succeeded), which
+// records index of func producing each error.
+//
+//    err := TilErrAsyncAll(initConn1, initConn2, initConn3)
+//    if errors.Is(err, ErrAuth) {
+//        // at least one of them failed to authorize
+//    }
+//    var me MultiError
+//    if errors.As(err, &me) {
+//        for _, e := range me {
+//            log.Printf("conn #%d failed: %v", e.Index+1, e.Err)
+//        }
+//    }
+func TilErrAsyncAll(funcs ...func() error) (err error) {
+	errs := make([]error, len(funcs))
+	wrapped := make([]func() error, len(funcs))
+	for idx, f := range funcs {
+		idx, f := idx, f
+		wrapped[idx] = func() error {
+			errs[idx] = f()
+			return errs[idx]
+		}
+	}
+
+	TilErrAsync(wrapped...)
+	return collectErrors(errs)
+}
@@ -70,3 +70,26 @@ func ExampleTilErrAsync() {
 	// api3
 	// an error occurred: failed to connect to api2
 }
+
+func ExampleTilErrAsyncAll() {
+	initConn1 := func() error {
+		return nil
+	}
+	initConn2 := func() error {
+		return errors.New("failed to connect to api2")
+	}
+	initConn3 := func() error {
+		return errors.New("failed to connect to api3")
+	}
+
+	err := TilErrAsyncAll(initConn1, initConn2, initConn3)
+	var me MultiError
+	if errors.As(err, &me) {
+		for _, e := range me {
+			fmt.Printf("api%d: %v\n", e.Index+1, e.Err)
+		}
+	}
+
+	// output: api2: failed to connect to api2
+	// api3: failed to connect to api3
+}
@@ -72,3 +72,44 @@ func TestTilErrAsync(t *testing.T) {
 		t.Errorf("expect fbad ran 2 times, got %d", cnt[1])
 	}
 }
+
+type errWithCode int
+
+func (e errWithCode) Error() string { return "code" }
+
+func TestTilErrAsyncAll(t *testing.T) {
+	err1 := errors.New("error1")
+	err2 := errWithCode(2)
+	fgood := func() error { return nil }
+
+	err := TilErrAsyncAll(fgood, func() error { return err1 }, fgood, func() error { return err2 })
+	if !errors.Is(err, err1) {
+		t.Error("expected err1 is included")
+	}
+	var code errWithCode
+	if !errors.As(err, &code) || code != 2 {
+		t.Error("expected err2 is included")
+	}
+
+	var me MultiError
+	if !errors.As(err, &me) {
+		t.Fatalf("expected MultiError, got %T", err)
+	}
+	if l := len(me); l != 2 {
+		t.Fatalf("expected 2 errors, got %d", l)
+	}
+	if me[0].Index != 1 || me[0].Err != err1 {
+		t.Errorf("unexpected first error: %v", me[0])
+	}
+	if me[1].Index != 3 || me[1].Err != err2 {
+		t.Errorf("unexpected second error: %v", me[1])
+	}
+}
+
+func TestTilErrAsyncAllDone(t *testing.T) {
+	fgood := func() error { return nil }
+
+	if err := TilErrAsyncAll(fgood, fgood); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+}
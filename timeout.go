@@ -0,0 +1,115 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is returned (or wrapped) by RunAtMost and its siblings if f runs
+// longer than the duration
+var ErrTimeout = errors.New("RunAtMost: timeout")
+
+// WithAbandon makes RunAtMost and its siblings return ErrTimeout at once when
+// time is up, without waiting f to return
+//
+// f keeps running in background until it honours cancellation, use with care.
+func WithAbandon() Option {
+	return func(o *options) {
+		o.abandon = true
+	}
+}
+
+// RunAtMost limits the execution time of f to dur
+//
+// The context passed to f is cancelled when dur is reached. By default, it
+// waits f to return and returns ErrTimeout, which also wraps the error returned
+// by f. See WithAbandon if you don't want to wait.
+//
+//    fetch := RunAtMost(time.Second, func(ctx context.Context) error {
+//        return fetchPage(ctx, url)
+//    })
+//    if err := fetch(ctx); errors.Is(err, ErrTimeout) {
+//        log.Print("server is too slow")
+//    }
+func RunAtMost(dur time.Duration, f func(ctx context.Context) error) func(ctx context.Context) error {
+	return RunAtMostOpts(dur, f)
+}
+
+// RunAtMostOpts is identical to RunAtMost, but accepts options
+//
+// Supported options: WithClock, WithObserver, WithName, WithAbandon
+func RunAtMostOpts(dur time.Duration, f func(ctx context.Context) error, opts ...Option) func(ctx context.Context) error {
+	return runAtMost(dur, f, newOptions(opts), func(error) bool { return true })
+}
+
+// RunSuccessAtMost is like RunAtMost, but a late failure is returned as-is
+//
+// In other words, only a successful execution exceeding dur is turned into
+// ErrTimeout. With WithAbandon, ErrTimeout is always returned as result is
+// unknown when time is up.
+func RunSuccessAtMost(dur time.Duration, f func(ctx context.Context) error) func(ctx context.Context) error {
+	return RunSuccessAtMostOpts(dur, f)
+}
+
+// RunSuccessAtMostOpts is identical to RunSuccessAtMost, but accepts options
+//
+// Supported options: WithClock, WithObserver, WithName, WithAbandon
+func RunSuccessAtMostOpts(dur time.Duration, f func(ctx context.Context) error, opts ...Option) func(ctx context.Context) error {
+	return runAtMost(dur, f, newOptions(opts), func(err error) bool { return err == nil })
+}
+
+// RunFailedAtMost is like RunAtMost, but a late success is returned as-is
+//
+// In other words, only a failed execution exceeding dur is turned into
+// ErrTimeout. With WithAbandon, ErrTimeout is always returned as result is
+// unknown when time is up.
+func RunFailedAtMost(dur time.Duration, f func(ctx context.Context) error) func(ctx context.Context) error {
+	return RunFailedAtMostOpts(dur, f)
+}
+
+// RunFailedAtMostOpts is identical to RunFailedAtMost, but accepts options
+//
+// Supported options: WithClock, WithObserver, WithName, WithAbandon
+func RunFailedAtMostOpts(dur time.Duration, f func(ctx context.Context) error, opts ...Option) func(ctx context.Context) error {
+	return runAtMost(dur, f, newOptions(opts), func(err error) bool { return err != nil })
+}
+
+// runAtMost turns late result into ErrTimeout if cond(result) is true
+func runAtMost(dur time.Duration, f func(ctx context.Context) error, o *options, cond func(error) bool) func(ctx context.Context) error {
+	return func(parent context.Context) (err error) {
+		ctx, cancel := context.WithCancel(parent)
+		defer cancel()
+
+		idx := o.next()
+		ch := make(chan error, 1)
+		go func() {
+			ch <- o.observe(idx, func() error { return f(ctx) })
+		}()
+
+		timer, stop := newTimer(o.clock, dur)
+		defer stop()
+		select {
+		case err = <-ch:
+			return
+		case <-timer:
+		}
+
+		cancel()
+		if o.abandon {
+			return ErrTimeout
+		}
+		if err = <-ch; !cond(err) {
+			return
+		}
+		if err == nil {
+			return ErrTimeout
+		}
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+}
@@ -0,0 +1,83 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/raohwork/routines/routinestest"
+)
+
+func TestRunAtMost(t *testing.T) {
+	theErr := errors.New("the error")
+	type wrapper func(time.Duration, func(context.Context) error, ...Option) func(context.Context) error
+	cases := []struct {
+		name    string
+		w       wrapper
+		late    error
+		timeout bool
+	}{
+		{name: "any/success", w: RunAtMostOpts, late: nil, timeout: true},
+		{name: "any/failed", w: RunAtMostOpts, late: theErr, timeout: true},
+		{name: "success/success", w: RunSuccessAtMostOpts, late: nil, timeout: true},
+		{name: "success/failed", w: RunSuccessAtMostOpts, late: theErr},
+		{name: "failed/success", w: RunFailedAtMostOpts, late: nil},
+		{name: "failed/failed", w: RunFailedAtMostOpts, late: theErr, timeout: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clock := routinestest.NewClock(time.Now())
+			f := c.w(time.Second, func(ctx context.Context) error {
+				<-ctx.Done()
+				return c.late
+			}, WithClock(clock))
+
+			done := make(chan error)
+			go func() { done <- f(context.Background()) }()
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+			err := <-done
+
+			if errors.Is(err, ErrTimeout) != c.timeout {
+				t.Fatal("unexpected error: ", err)
+			}
+			if c.late != nil && !errors.Is(err, c.late) {
+				t.Fatal("expected to wrap error of f, got ", err)
+			}
+		})
+	}
+}
+
+func TestRunAtMostInTime(t *testing.T) {
+	theErr := errors.New("the error")
+	for _, e := range []error{nil, theErr} {
+		err := RunAtMost(time.Hour, func(context.Context) error { return e })(context.Background())
+		if err != e {
+			t.Fatalf("expected %v, got %v", e, err)
+		}
+	}
+}
+
+func TestRunAtMostAbandon(t *testing.T) {
+	c := routinestest.NewClock(time.Now())
+	release := make(chan struct{})
+	defer close(release)
+	f := RunSuccessAtMostOpts(time.Second, func(context.Context) error {
+		<-release // does not honour cancellation
+		return nil
+	}, WithClock(c), WithAbandon())
+
+	done := make(chan error)
+	go func() { done <- f(context.Background()) }()
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	if err := <-done; err != ErrTimeout {
+		t.Fatal("unexpected error: ", err)
+	}
+}
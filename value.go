@@ -0,0 +1,112 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// RecordedValue is identical to Recorded, but f returns a value
+func RecordedValue[T any](f func(idx uint64) (T, error)) (ret func() (T, error)) {
+	x := uint64(0)
+	tries := &x
+
+	return func() (v T, err error) {
+		v, err = f(atomic.LoadUint64(tries))
+		atomic.AddUint64(tries, 1)
+		return
+	}
+}
+
+// triesValue mirrors TriesAtMost, but sends value of successful attempt to result
+//
+// result is buffered and closed right after err, so it is safe to read it after
+// consuming all errors. Like Retry, next attempt starts after the error is
+// consumed.
+func triesValue[T any](n uint64, f func() (T, error)) (err chan error, result chan T) {
+	err = make(chan error)
+	result = make(chan T, 1)
+
+	go func() {
+		defer close(result)
+		defer close(err)
+		for idx := uint64(0); idx < n; idx++ {
+			v, e := f()
+			if e == nil {
+				result <- v
+				return
+			}
+
+			err <- e
+			if IsPermanent(e) {
+				return
+			}
+		}
+	}()
+
+	return
+}
+
+// RetryValue is identical to Retry, but f returns a value
+//
+// Value returned by successful attempt can be read from result after err is
+// closed, and result is closed then. result is closed without any value if Retry gives up,
+// like f returns a PermanentError.
+//
+//    errs, result := RetryValue(fetchPage)
+//    for e := range errs {
+//        log.Print("failed to fetch: ", e)
+//    }
+//    page, ok := <-result
+func RetryValue[T any](f func() (T, error)) (err chan error, result chan T) {
+	return triesValue(math.MaxUint64, f) // practically forever
+}
+
+// TriesAtMostValue is identical to TriesAtMost, but f returns a value
+//
+// Like RetryValue, result is closed after err is closed. It is closed without any
+// value if all attempts failed.
+//
+//    errs, result := TriesAtMostValue(3, fetchPage)
+//    for e := range errs {
+//        log.Print("failed to fetch: ", e)
+//    }
+//    page, ok := <-result
+func TriesAtMostValue[T any](n uint64, f func() (T, error)) (err chan error, result chan T) {
+	return triesValue(n, f)
+}
+
+// TryAtMostValue wraps TriesAtMostValue, returns value of successful attempt, or
+// last error iff all attempts failed.
+func TryAtMostValue[T any](n uint64, f func() (T, error)) (ret T, err error) {
+	ch, result := TriesAtMostValue(n, f)
+	for err = range ch {
+	}
+
+	if v, ok := <-result; ok {
+		return v, nil
+	}
+	return
+}
+
+// AllAsync is identical to TilErrAsync, but f returns a value
+//
+// Values are returned in same order as funcs, no matter which one is done first.
+// Value of failed function is also included, which is often zero value.
+func AllAsync[T any](funcs ...func() (T, error)) (ret []T, err error) {
+	ret = make([]T, len(funcs))
+	wrapped := make([]func() error, len(funcs))
+	for idx, f := range funcs {
+		idx, f := idx, f
+		wrapped[idx] = func() (err error) {
+			ret[idx], err = f()
+			return
+		}
+	}
+
+	err = TilErrAsync(wrapped...)
+	return
+}
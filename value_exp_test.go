@@ -0,0 +1,52 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+func ExampleRetryValue() {
+	fetch := RecordedValue(func(idx uint64) (string, error) {
+		if idx <= 1 {
+			return "", errors.New("network error")
+		}
+		return "content", nil
+	})
+
+	errs, result := RetryValue(fetch)
+	for e := range errs {
+		fmt.Println(e)
+	}
+	fmt.Println(<-result)
+
+	// output: network error
+	// network error
+	// content
+}
+
+func ExampleAllAsync() {
+	// say you have to query 3 independant apis
+	api := func(name string, d time.Duration) func() (string, error) {
+		return func() (string, error) {
+			time.Sleep(d)
+			return name, nil
+		}
+	}
+
+	ret, err := AllAsync(
+		api("api1", 3*time.Millisecond),
+		api("api2", 2*time.Millisecond),
+		api("api3", time.Millisecond),
+	)
+	if err != nil {
+		fmt.Println("an error occurred:", err)
+	}
+	fmt.Println(ret)
+
+	// output: [api1 api2 api3]
+}
@@ -0,0 +1,152 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package routines
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryValue(t *testing.T) {
+	theErr := errors.New("the error")
+	f := RecordedValue(func(i uint64) (uint64, error) {
+		if i >= 5 {
+			return i, nil
+		}
+		return 0, theErr
+	})
+
+	ch, result := RetryValue(f)
+	cnt := 0
+	for err := range ch {
+		cnt++
+		if err != theErr {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if cnt != 5 {
+		t.Fatalf("expected to failed 5 times, got %d", cnt)
+	}
+
+	v, ok := <-result
+	if !ok || v != 5 {
+		t.Fatalf("expected 5, got %d (%v)", v, ok)
+	}
+	if _, ok := <-result; ok {
+		t.Fatal("expected result to be closed")
+	}
+}
+
+func TestTriesAtMostValueFailed(t *testing.T) {
+	theErr := errors.New("the error")
+	ch, result := TriesAtMostValue(3, func() (int, error) {
+		return 1, theErr
+	})
+
+	cnt := 0
+	for range ch {
+		cnt++
+	}
+	if cnt != 3 {
+		t.Fatalf("expected to failed 3 times, got %d", cnt)
+	}
+	if v, ok := <-result; ok {
+		t.Fatalf("expected no value, got %d", v)
+	}
+}
+
+func TestTryAtMostValue(t *testing.T) {
+	theErr := errors.New("the error")
+	f := RecordedValue(func(i uint64) (uint64, error) {
+		if i >= 3 {
+			return i, nil
+		}
+		return i, theErr
+	})
+
+	v, err := TryAtMostValue(3, f)
+	if err != theErr {
+		t.Fatal("unexpected error: ", err)
+	}
+	if v != 0 {
+		t.Fatal("expected zero value, got ", v)
+	}
+
+	v, err = TryAtMostValue(3, f)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if v != 3 {
+		t.Fatal("expected 3, got ", v)
+	}
+}
+
+func TestAllAsync(t *testing.T) {
+	theErr := errors.New("the error")
+	mk := func(v int, d time.Duration, err error) func() (int, error) {
+		return func() (int, error) {
+			time.Sleep(d)
+			return v, err
+		}
+	}
+
+	ret, err := AllAsync(
+		mk(1, 3*time.Millisecond, nil),
+		mk(2, 0, nil),
+		mk(3, time.Millisecond, theErr),
+	)
+	if err != theErr {
+		t.Fatal("unexpected error: ", err)
+	}
+	expect := []int{1, 2, 3}
+	for idx, v := range expect {
+		if ret[idx] != v {
+			t.Fatalf("expected %v, got %v", expect, ret)
+		}
+	}
+}
+
+func TestRecordedValueConcurrent(t *testing.T) {
+	f := RecordedValue(func(i uint64) (uint64, error) {
+		return i + 1, nil
+	})
+
+	done := make(chan uint64)
+	for i := 0; i < 10; i++ {
+		go func() {
+			v, _ := f()
+			done <- v
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		if v := <-done; v == 0 {
+			t.Fatal("unexpected zero value")
+		}
+	}
+}
+
+func TestRetryValueBackpressure(t *testing.T) {
+	var cnt int32
+	ch, result := RetryValue(func() (int, error) {
+		if atomic.AddInt32(&cnt, 1) == 1 {
+			return 0, errors.New("")
+		}
+		return 1, nil
+	})
+
+	// like Retry, second attempt waits for the error being consumed
+	time.Sleep(10 * time.Millisecond)
+	if c := atomic.LoadInt32(&cnt); c != 1 {
+		t.Fatalf("expected 1 attempt before consuming, got %d", c)
+	}
+
+	for range ch {
+	}
+	if v, ok := <-result; !ok || v != 1 {
+		t.Fatalf("unexpected result: %d, %v", v, ok)
+	}
+}